@@ -0,0 +1,169 @@
+// eval 对生成提示词做回归评测：每种学习类型调用模型 N 次，统计解析成功率、
+// 字段完整率、与已学内容的重复率、平均关键词数和耗时，输出 JSON 和 markdown 表格。
+//
+//	go run ./cmd/eval -n 20 -types chinese,tcm -out report.json
+//	go run ./cmd/eval -provider mock -cassettes ./cassettes -n 50
+//	go run ./cmd/eval -prompt ./prompts/chinese-v2.txt -types chinese
+//	go run ./cmd/eval -injection -min-parse-rate 1 -min-complete-rate 1
+//
+// 其余配置（密钥、回退链、数据库路径等）与服务本身相同，从环境变量和 .env 读取。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/generator"
+	"everyday-study-backend/internal/models"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func main() {
+	types := flag.String("types", strings.Join(models.GetAllLearningTypes(), ","), "要评测的学习类型，逗号分隔")
+	runs := flag.Int("n", 10, "每种类型的调用次数")
+	provider := flag.String("provider", "", "大模型提供方：volcano 或 mock，默认沿用 LLM_PROVIDER")
+	cassettes := flag.String("cassettes", "", "mock 模式的录制目录")
+	failures := flag.String("fail", "", "mock 模式的故障注入，例如 500:0.2,malformed:0.1")
+	model := flag.String("model", "", "覆盖回退链第一个模型")
+	promptFile := flag.String("prompt", "", "自定义生成提示词文件，需给出 JSON 输出格式，已学内容由用户消息提供")
+	dbPath := flag.String("db", "", "读取已学内容的数据库，默认沿用 DATABASE_PATH")
+	out := flag.String("out", "", "JSON 报告输出路径")
+	mdOut := flag.String("md", "", "markdown 表格输出路径，默认输出到标准输出")
+	minParseRate := flag.Float64("min-parse-rate", 0, "任一类型解析成功率低于该值时以非零状态退出")
+	minCompleteRate := flag.Float64("min-complete-rate", 0, "任一类型字段完整率低于该值时以非零状态退出")
+	injection := flag.Bool("injection", false, "在已学内容中混入提示词注入样本，检验输出格式不被篡改")
+	flag.Parse()
+
+	// 命令行参数优先于环境变量
+	setEnv("LLM_PROVIDER", *provider)
+	setEnv("MOCK_CASSETTE_DIR", *cassettes)
+	setEnv("MOCK_FAILURES", *failures)
+	setEnv("DATABASE_PATH", *dbPath)
+
+	cfg := config.Load()
+
+	var learningTypes []string
+	for _, t := range strings.Split(*types, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !models.IsValidLearningType(t) {
+			log.Fatalf("无效的学习类型: %s", t)
+		}
+		learningTypes = append(learningTypes, t)
+	}
+	if len(learningTypes) == 0 || *runs <= 0 {
+		log.Fatal("至少需要一种学习类型，且调用次数大于 0")
+	}
+
+	var customPrompt string
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			log.Fatalf("读取提示词文件失败: %v", err)
+		}
+		customPrompt = string(data)
+		if err := config.ValidateSystemPrompt(customPrompt); err != nil {
+			log.Fatalf("提示词文件无效: %v", err)
+		}
+	}
+	for _, t := range learningTypes {
+		profile := cfg.Profile(t)
+		if *model != "" {
+			profile.Chain = append([]config.ModelTier{{Model: *model}}, profile.Chain...)
+		}
+		if customPrompt != "" {
+			profile.SystemPrompt = customPrompt
+		}
+		cfg.Profiles[t] = profile
+	}
+
+	if _, err := database.Init(cfg); err != nil {
+		log.Fatalf("数据库初始化失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("🧪 开始评测 %s，每种类型 %d 次", strings.Join(learningTypes, ", "), *runs)
+	if *injection {
+		log.Printf("💉 已学内容中混入 %d 条注入样本", len(generator.InjectionSamples))
+	}
+	report, err := generator.New(ctx, cfg).Evaluate(ctx, learningTypes, *runs, *injection)
+	if err != nil {
+		log.Fatalf("评测失败: %v", err)
+	}
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("序列化报告失败: %v", err)
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			log.Fatalf("写入报告失败: %v", err)
+		}
+		log.Printf("📄 JSON 报告已写入 %s", *out)
+	}
+
+	var w io.Writer = os.Stdout
+	if *mdOut != "" {
+		f, err := os.Create(*mdOut)
+		if err != nil {
+			log.Fatalf("创建 markdown 报告失败: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	writeMarkdown(w, report)
+
+	for _, result := range report.Results {
+		if result.ParseSuccessRate < *minParseRate {
+			log.Printf("❌ %s 解析成功率 %.0f%% 低于阈值 %.0f%%", result.Type, result.ParseSuccessRate*100, *minParseRate*100)
+			os.Exit(1)
+		}
+		if result.SchemaCompleteRate < *minCompleteRate {
+			log.Printf("❌ %s 字段完整率 %.0f%% 低于阈值 %.0f%%", result.Type, result.SchemaCompleteRate*100, *minCompleteRate*100)
+			os.Exit(1)
+		}
+	}
+}
+
+func setEnv(key, value string) {
+	if value != "" {
+		os.Setenv(key, value)
+	}
+}
+
+func writeMarkdown(w io.Writer, report *generator.EvalReport) {
+	title := "提示词评测报告"
+	if report.Injection {
+		title = "提示词注入评测报告"
+	}
+	fmt.Fprintf(w, "## %s（%s，%s）\n\n", title, report.Provider, report.GeneratedAt)
+	fmt.Fprintln(w, "| 类型 | 模型 | 提示词版本 | 次数 | 请求失败 | 解析成功率 | 字段完整率 | 重复率 | 不重复占比 | 平均关键词数 | 平均耗时(ms) | 平均token |")
+	fmt.Fprintln(w, "| --- | --- | --- | ---: | ---: | ---: | ---: | ---: | ---: | ---: | ---: | ---: |")
+	for _, r := range report.Results {
+		fmt.Fprintf(w, "| %s | %s | %s | %d | %d | %.1f%% | %.1f%% | %.1f%% | %.1f%% | %.1f | %.0f | %.0f |\n",
+			r.Type, r.Model, r.PromptVersion, r.Runs, r.RequestErrors,
+			r.ParseSuccessRate*100, r.SchemaCompleteRate*100, r.DuplicateRate*100, r.UniqueRate*100,
+			r.AvgKeyWords, r.AvgLatencyMs, r.AvgTotalTokens)
+	}
+
+	for _, r := range report.Results {
+		if len(r.SampleErrors) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n**%s 错误示例**\n\n", r.Type)
+		for _, e := range r.SampleErrors {
+			fmt.Fprintf(w, "- %s\n", e)
+		}
+	}
+}
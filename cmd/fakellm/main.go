@@ -0,0 +1,65 @@
+// fakellm 是独立运行的模拟大模型服务，兼容 /chat/completions 接口。
+// 将 VOLCANO_BASE_URL 指向它即可在不消耗额度的情况下联调整个服务：
+//
+//	go run ./cmd/fakellm -addr :19999 -cassettes ./cassettes -fail "500:0.1,timeout:0.05"
+//	VOLCANO_BASE_URL=http://127.0.0.1:19999 VOLCANO_API_KEY=fake go run .
+//
+// 指定 -upstream 时进入录制模式，请求转发到真实接口，响应保存到录制目录供之后回放。
+package main
+
+import (
+	"everyday-study-backend/internal/mockllm"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":19999", "监听地址")
+	cassettes := flag.String("cassettes", "", "录制文件目录，为空时使用内置录制")
+	failures := flag.String("fail", "", "故障注入，例如 500:0.2,timeout:0.1,malformed:0.05,empty")
+	latency := flag.Duration("latency", 0, "每次响应前的延迟，例如 500ms")
+	upstream := flag.String("upstream", "", "录制模式：真实接口地址，例如 https://ark.cn-beijing.volces.com/api/v3")
+	flag.Parse()
+
+	failureList, err := mockllm.ParseFailures(*failures)
+	if err != nil {
+		log.Fatalf("故障注入配置无效: %v", err)
+	}
+
+	opts := mockllm.Options{
+		CassetteDir: *cassettes,
+		Failures:    failureList,
+		Latency:     *latency,
+		UpstreamURL: *upstream,
+	}
+	if *upstream != "" {
+		opts.UpstreamKey = os.Getenv("VOLCANO_API_KEY")
+		if opts.UpstreamKey == "" {
+			log.Fatal("录制模式需要设置 VOLCANO_API_KEY")
+		}
+	}
+
+	server, err := mockllm.New(opts)
+	if err != nil {
+		log.Fatalf("初始化模拟服务失败: %v", err)
+	}
+
+	if *upstream != "" {
+		log.Printf("📼 录制模式：转发到 %s，响应保存到 %s", *upstream, *cassettes)
+	} else {
+		log.Printf("📼 回放模式：已加载 %d 条录制响应", server.Cassette().Len())
+	}
+	log.Printf("🧪 模拟大模型服务启动在 %s", *addr)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("服务启动失败: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/api"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/generator"
+	"everyday-study-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 各学习类型的原文字段
+var contentFields = map[string]string{
+	"english": "proverb",
+	"chinese": "poem",
+	"tcm":     "tcm_text",
+}
+
+const cleanLearned = "床前明月光，疑是地上霜。—— 唐 李白 《静夜思》"
+
+// captureMessages 启动一个假的模型服务，记录每次请求的系统提示词和用户消息
+func captureMessages(t *testing.T) (*api.VolcanoClient, func() (string, string)) {
+	t.Helper()
+	var system, user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.VolcanoAPIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		for _, m := range req.Messages {
+			switch m.Role {
+			case "system":
+				system = m.Content
+			case "user":
+				user = m.Content
+			}
+		}
+		json.NewEncoder(w).Encode(models.VolcanoAPIResponse{
+			Choices: []models.Choice{{Message: models.Message{Role: "assistant", Content: "{}"}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := api.NewVolcanoClient(&config.Config{VolcanoBaseURL: server.URL, VolcanoAPIKey: "test"})
+	return client, func() (string, string) { return system, user }
+}
+
+func TestInjectionSamplesOnlyReachUserMessageAsJSON(t *testing.T) {
+	client, last := captureMessages(t)
+	ctx := context.Background()
+
+	for learningType := range contentFields {
+		for _, settings := range []api.CallSettings{
+			{Type: learningType},
+			{Type: learningType, SystemPrompt: "自定义提示词，只返回JSON对象"},
+		} {
+			if _, err := client.CallVolcanoAPI(ctx, settings, learningType, []string{cleanLearned}); err != nil {
+				t.Fatalf("调用失败: %v", err)
+			}
+			cleanSystem, _ := last()
+
+			for _, sample := range generator.InjectionSamples {
+				if _, err := client.CallVolcanoAPI(ctx, settings, learningType, []string{cleanLearned, sample}); err != nil {
+					t.Fatalf("调用失败: %v", err)
+				}
+				system, user := last()
+
+				if system != cleanSystem {
+					t.Errorf("%s: 混入样本后系统提示词发生变化: %q", learningType, sample)
+				}
+
+				start := strings.Index(user, `{"learned":`)
+				if start < 0 {
+					t.Fatalf("%s: 用户消息中没有 learned 数据: %q", learningType, user)
+				}
+				dec := json.NewDecoder(strings.NewReader(user[start:]))
+				var payload struct {
+					Learned []string `json:"learned"`
+				}
+				if err := dec.Decode(&payload); err != nil {
+					t.Fatalf("%s: learned 数据不是合法的 JSON: %v", learningType, err)
+				}
+				raw := user[start : start+int(dec.InputOffset())]
+				outside := user[:start] + user[start+int(dec.InputOffset()):]
+
+				// 引号、尖括号和换行都被转义，样本无法闭合 JSON 或伪造标签
+				if strings.ContainsAny(raw, "<>\n") {
+					t.Errorf("%s: learned 数据中有未转义的字符: %s", learningType, raw)
+				}
+				body := strings.Join(strings.Fields(strings.SplitN(sample, "——", 2)[0]), " ")
+				for _, item := range payload.Learned[1:] {
+					if !strings.HasPrefix(body, item) {
+						t.Errorf("%s: learned 中出现了样本以外的内容: %q", learningType, item)
+					}
+					if strings.Contains(outside, item) || strings.Contains(system, item) {
+						t.Errorf("%s: 样本出现在 learned 数据之外: %q", learningType, item)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParserRejectsInjectedOutput(t *testing.T) {
+	for learningType, field := range contentFields {
+		valid, _ := json.Marshal(map[string]interface{}{field: cleanLearned, "interpretation": "思乡"})
+		if _, err := generator.ParseAIContent(string(valid), learningType); err != nil {
+			t.Fatalf("%s: 合法输出解析失败: %v", learningType, err)
+		}
+
+		for _, sample := range generator.InjectionSamples {
+			withoutField, _ := json.Marshal(map[string]interface{}{"content": sample, "interpretation": sample, "key_words": []string{}})
+			withoutInterpretation, _ := json.Marshal(map[string]interface{}{field: sample, "key_words": []string{}})
+			outputs := []string{sample, sample + string(valid)[1:], string(withoutField), string(withoutInterpretation)}
+			for _, output := range outputs {
+				if parsed, err := generator.ParseAIContent(output, learningType); err == nil {
+					t.Errorf("%s: 缺少必需字段的输出被接受: %q => %+v", learningType, output, parsed)
+				}
+			}
+		}
+	}
+}
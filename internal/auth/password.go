@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DummyPasswordHash 是一个固定的 bcrypt 哈希（与 HashPassword 相同的 cost），不对应任何用户。
+// 登录的用户不存在时仍用它比较一次密码，避免通过响应时间判断用户名是否存在
+const DummyPasswordHash = "$2a$10$RrzYqbtoJEiITeLWgsxTRe0HW12qjGkkrOm3R7xoPQzfsZBTf7qGm"
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
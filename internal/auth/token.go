@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const issuer = "everyday-study"
+
+// 令牌类型，写入 typ 声明，防止 refresh token 被当作 access token 使用
+const (
+	KindAccess  = "access"
+	KindRefresh = "refresh"
+)
+
+var ErrInvalidToken = errors.New("无效的令牌")
+
+type Claims struct {
+	Username string `json:"username"`
+	Kind     string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID 返回令牌所属的用户ID
+func (c *Claims) UserID() uint {
+	id, _ := strconv.ParseUint(c.Subject, 10, 64)
+	return uint(id)
+}
+
+// Token 是签发的令牌及其ID和过期时间
+type Token struct {
+	Value     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// Manager 使用 HS256 签发和校验用户的 access token 与 refresh token
+type Manager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewManager(secret string, accessTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *Manager) IssueAccess(userID uint, username string) (*Token, error) {
+	return m.issue(userID, username, KindAccess, m.accessTTL)
+}
+
+// IssueRefresh 签发 refresh token，调用方需保存令牌ID以便轮换和吊销
+func (m *Manager) IssueRefresh(userID uint, username string) (*Token, error) {
+	return m.issue(userID, username, KindRefresh, m.refreshTTL)
+}
+
+func (m *Manager) issue(userID uint, username, kind string, ttl time.Duration) (*Token, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		Username: username,
+		Kind:     kind,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			ID:        id,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	value, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return nil, fmt.Errorf("签发令牌失败: %v", err)
+	}
+	return &Token{Value: value, ID: id, ExpiresAt: expiresAt}, nil
+}
+
+// Parse 校验签名、过期时间和令牌类型，通过后返回其中的声明
+func (m *Manager) Parse(value, kind string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(value, &claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
+	if err != nil || claims.Kind != kind || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func (m *Manager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成令牌ID失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,122 @@
+package calendar
+
+import (
+	"math"
+	"time"
+)
+
+// 天文计算采用 Meeus《天文算法》中的低精度公式：太阳视黄经误差约 0.01°（约 15 分钟），
+// 朔日时刻误差在几分钟以内，足以确定节气和农历月初所在的日期。
+
+const (
+	j2000        = 2451545.0
+	tropicalYear = 365.2422
+	synodicMonth = 29.530588861
+)
+
+// 农历和节气都按北京时间确定日期
+var beijing = time.FixedZone("CST", 8*3600)
+
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func normalize(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// sunLongitude 返回儒略日 jd 时太阳的视黄经（度）
+func sunLongitude(jd float64) float64 {
+	t := (jd - j2000) / 36525
+	l0 := 280.46646 + 36000.76983*t + 0.0003032*t*t
+	m := rad(357.52911 + 35999.05029*t - 0.0001537*t*t)
+	c := (1.914602-0.004817*t-0.000014*t*t)*math.Sin(m) +
+		(0.019993-0.000101*t)*math.Sin(2*m) +
+		0.000289*math.Sin(3*m)
+	omega := rad(125.04 - 1934.136*t)
+	return normalize(l0 + c - 0.00569 - 0.00478*math.Sin(omega))
+}
+
+// solarLongitudeTime 返回 guess 附近太阳视黄经到达 target 度的儒略日
+func solarLongitudeTime(target float64, guess float64) float64 {
+	jd := guess
+	for i := 0; i < 10; i++ {
+		diff := normalize(target-sunLongitude(jd)+180) - 180
+		jd += diff * tropicalYear / 360
+		if math.Abs(diff) < 1e-6 {
+			break
+		}
+	}
+	return jd
+}
+
+// newMoon 返回第 k 个朔（k=0 为 2000 年 1 月 6 日附近的朔）的儒略日
+func newMoon(k float64) float64 {
+	t := k / 1236.85
+	jd := 2451550.09766 + synodicMonth*k + 0.00015437*t*t - 0.000000150*t*t*t + 0.00000000073*t*t*t*t
+	e := 1 - 0.002516*t - 0.0000074*t*t
+	m := rad(2.5534 + 29.10535670*k - 0.0000014*t*t - 0.00000011*t*t*t)
+	mp := rad(201.5643 + 385.81693528*k + 0.0107582*t*t + 0.00001238*t*t*t - 0.000000058*t*t*t*t)
+	f := rad(160.7108 + 390.67050284*k - 0.0016118*t*t - 0.00000227*t*t*t + 0.000000011*t*t*t*t)
+	omega := rad(124.7746 - 1.56375588*k + 0.0020672*t*t + 0.00000215*t*t*t)
+
+	jd += -0.40720*math.Sin(mp) +
+		0.17241*e*math.Sin(m) +
+		0.01608*math.Sin(2*mp) +
+		0.01039*math.Sin(2*f) +
+		0.00739*e*math.Sin(mp-m) -
+		0.00514*e*math.Sin(mp+m) +
+		0.00208*e*e*math.Sin(2*m) -
+		0.00111*math.Sin(mp-2*f) -
+		0.00057*math.Sin(mp+2*f) +
+		0.00056*e*math.Sin(2*mp+m) -
+		0.00042*math.Sin(3*mp) +
+		0.00042*e*math.Sin(m+2*f) +
+		0.00038*e*math.Sin(m-2*f) -
+		0.00024*e*math.Sin(2*mp-m) -
+		0.00017*math.Sin(omega) -
+		0.00007*math.Sin(mp+2*m) +
+		0.00004*math.Sin(2*mp-2*f) +
+		0.00004*math.Sin(3*m) +
+		0.00003*math.Sin(mp+m-2*f) +
+		0.00003*math.Sin(2*mp+2*f) -
+		0.00003*math.Sin(mp+m+2*f) +
+		0.00003*math.Sin(mp-m+2*f) -
+		0.00002*math.Sin(mp-m-2*f) -
+		0.00002*math.Sin(3*mp+m) +
+		0.00002*math.Sin(4*mp)
+	return jd
+}
+
+// newMoonOnOrBefore 返回不晚于 jd 所在北京日期的最近一次朔的日期（北京时间零点）
+func newMoonOnOrBefore(day time.Time) time.Time {
+	jd := julianDay(day.Add(24 * time.Hour))
+	k := math.Floor((jd - 2451550.09766) / synodicMonth)
+	for {
+		moon := beijingDate(newMoon(k))
+		if !moon.After(day) {
+			next := beijingDate(newMoon(k + 1))
+			if next.After(day) {
+				return moon
+			}
+			k++
+			continue
+		}
+		k--
+	}
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + 2440587.5
+}
+
+// beijingDate 把儒略日（力学时，与世界时相差约一分钟，忽略）换算为北京时间的日期
+func beijingDate(jd float64) time.Time {
+	sec := (jd - 2440587.5) * 86400
+	t := time.Unix(int64(math.Floor(sec)), 0).In(beijing)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, beijing)
+}
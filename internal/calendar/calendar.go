@@ -0,0 +1,152 @@
+// Package calendar 在本地计算二十四节气和农历传统节日的日期，不依赖外部服务。
+package calendar
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	KindSolarTerm = "solar_term"
+	KindFestival  = "festival"
+)
+
+// Occasion 是某一天的节气或传统节日，Date 为北京时间的日期
+type Occasion struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Date string `json:"date"`
+}
+
+// 一个公历年内的二十四节气，按时间顺序排列，Longitude 为对应的太阳视黄经
+var solarTerms = []struct {
+	Name      string
+	Longitude float64
+}{
+	{"小寒", 285}, {"大寒", 300}, {"立春", 315}, {"雨水", 330}, {"惊蛰", 345}, {"春分", 0},
+	{"清明", 15}, {"谷雨", 30}, {"立夏", 45}, {"小满", 60}, {"芒种", 75}, {"夏至", 90},
+	{"小暑", 105}, {"大暑", 120}, {"立秋", 135}, {"处暑", 150}, {"白露", 165}, {"秋分", 180},
+	{"寒露", 195}, {"霜降", 210}, {"立冬", 225}, {"小雪", 240}, {"大雪", 255}, {"冬至", 270},
+}
+
+// 农历传统节日：月、日
+var festivals = []struct {
+	Name  string
+	Month int
+	Day   int
+}{
+	{"春节", 1, 1}, {"元宵", 1, 15}, {"端午", 5, 5}, {"七夕", 7, 7}, {"中秋", 8, 15}, {"重阳", 9, 9},
+}
+
+// SolarTerms 返回公历 year 年的二十四节气
+func SolarTerms(year int) []Occasion {
+	equinox := julianDay(time.Date(year, time.March, 20, 0, 0, 0, 0, time.UTC))
+
+	result := make([]Occasion, 0, len(solarTerms))
+	for _, term := range solarTerms {
+		guess := equinox + term.Longitude*tropicalYear/360
+		if term.Longitude >= 285 {
+			guess -= tropicalYear
+		}
+		jd := solarLongitudeTime(term.Longitude, guess)
+		result = append(result, Occasion{
+			Name: term.Name,
+			Kind: KindSolarTerm,
+			Date: beijingDate(jd).Format("2006-01-02"),
+		})
+	}
+	return result
+}
+
+// Festivals 返回公历 year 年的农历传统节日
+func Festivals(year int) []Occasion {
+	months := lunarMonths(year)
+
+	var result []Occasion
+	for _, f := range festivals {
+		for _, m := range months {
+			if m.number == f.Month && !m.leap {
+				result = append(result, Occasion{
+					Name: f.Name,
+					Kind: KindFestival,
+					Date: m.start.AddDate(0, 0, f.Day-1).Format("2006-01-02"),
+				})
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Occasions 返回公历 year 年的全部节气和节日，按日期排序
+func Occasions(year int) []Occasion {
+	result := append(SolarTerms(year), Festivals(year)...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+	return result
+}
+
+// OccasionsOn 返回某一天的节气和节日，没有时返回空
+func OccasionsOn(day time.Time) []Occasion {
+	date := day.Format("2006-01-02")
+
+	var result []Occasion
+	for _, o := range Occasions(day.Year()) {
+		if o.Date == date {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+type lunarMonth struct {
+	start  time.Time
+	number int
+	leap   bool
+}
+
+// lunarMonths 返回从上一年冬至所在月（十一月）起，到当年冬至所在月之前的农历月，
+// 覆盖农历当年的正月到十月。两个冬至之间有 13 个月时，第一个不含中气的月为闰月。
+func lunarMonths(year int) []lunarMonth {
+	first := newMoonOnOrBefore(winterSolstice(year - 1))
+	last := newMoonOnOrBefore(winterSolstice(year))
+
+	var starts []time.Time
+	for start := first; start.Before(last); start = newMoonOnOrBefore(start.AddDate(0, 0, 31)) {
+		starts = append(starts, start)
+	}
+	starts = append(starts, last)
+
+	leapIndex := -1
+	if len(starts)-1 == 13 {
+		for i := 1; i < len(starts)-1; i++ {
+			if !hasPrincipalTerm(starts[i], starts[i+1]) {
+				leapIndex = i
+				break
+			}
+		}
+	}
+
+	months := make([]lunarMonth, 0, len(starts)-1)
+	number := 11
+	for i := 0; i < len(starts)-1; i++ {
+		leap := i == leapIndex
+		if i > 0 && !leap {
+			number = number%12 + 1
+		}
+		months = append(months, lunarMonth{start: starts[i], number: number, leap: leap})
+	}
+	return months
+}
+
+// hasPrincipalTerm 判断 [start, end) 期间是否有中气（太阳视黄经为 30° 的整数倍）
+func hasPrincipalTerm(start, end time.Time) bool {
+	return math.Floor(sunLongitude(julianDay(start))/30) != math.Floor(sunLongitude(julianDay(end))/30)
+}
+
+func winterSolstice(year int) time.Time {
+	guess := julianDay(time.Date(year, time.December, 21, 0, 0, 0, 0, time.UTC))
+	return beijingDate(solarLongitudeTime(270, guess))
+}
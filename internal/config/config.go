@@ -1,20 +1,59 @@
 package config
 
 import (
+	"everyday-study-backend/internal/models"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port            string
-	Environment     string
-	DatabasePath    string
-	VolcanoAPIKey   string
-	VolcanoBaseURL  string
+	Port           string
+	Environment    string
+	DatabasePath   string
+	VolcanoAPIKey  string
+	VolcanoBaseURL string
+	// 使用本地语料挑选原文、模型只负责解释的学习类型
+	CorpusModeTypes []string
+	// 在节气和传统节日自动按当天主题生成的学习类型
+	AutoThemeTypes []string
+	// 每日、每月的 token 预算，0 表示不限制；超出后停止调用模型
+	DailyTokenBudget   int64
+	MonthlyTokenBudget int64
+	// 访问 /admin 接口的 Bearer Token 和 HMAC 签名密钥，都未配置时不启用管理接口；
+	// 名称记入审计日志
+	AdminTokens   []AdminKey
+	AdminHMACKeys []AdminKey
+	// 签发用户 JWT 的密钥，未配置时不启用用户注册登录，所有接口按匿名访问
+	JWTSecret string
+	// access token 和 refresh token 的有效期
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// 各学习类型的模型参数、提示词和回退链
+	Profiles map[string]GenerationProfile
+	// 大模型提供方：volcano 或 mock（回放录制的响应，无需密钥即可离线运行）
+	LLMProvider string
+	// mock 模式的录制目录（为空时使用内置录制）、故障注入配置和响应延迟
+	MockCassetteDir string
+	MockFailures    string
+	MockLatencyMs   int64
 }
 
+// AdminKey 是一个管理员凭据，配置格式为 name:secret，省略名称时记为 admin
+type AdminKey struct {
+	Name   string
+	Secret string
+}
+
+const (
+	ProviderVolcano = "volcano"
+	ProviderMock    = "mock"
+)
+
 func Load() *Config {
 	// 加载 .env 文件
 	if err := godotenv.Load(); err != nil {
@@ -22,17 +61,47 @@ func Load() *Config {
 	}
 
 	cfg := &Config{
-		Port:           getEnv("PORT", "91"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
-		DatabasePath:   getEnv("DATABASE_PATH", "learning.db"),
-		VolcanoAPIKey:  getEnv("VOLCANO_API_KEY", ""),
-		VolcanoBaseURL: getEnv("VOLCANO_BASE_URL", "https://ark.cn-beijing.volces.com/api/v3"),
+		Port:               getEnv("PORT", "91"),
+		Environment:        getEnv("ENVIRONMENT", "development"),
+		DatabasePath:       getEnv("DATABASE_PATH", "learning.db"),
+		VolcanoAPIKey:      getEnv("VOLCANO_API_KEY", ""),
+		VolcanoBaseURL:     getEnv("VOLCANO_BASE_URL", "https://ark.cn-beijing.volces.com/api/v3"),
+		CorpusModeTypes:    getEnvList("CORPUS_MODE_TYPES"),
+		AutoThemeTypes:     getEnvListDefault("AUTO_THEME_TYPES", []string{"chinese", "tcm"}),
+		DailyTokenBudget:   getEnvInt64("DAILY_TOKEN_BUDGET", 0),
+		MonthlyTokenBudget: getEnvInt64("MONTHLY_TOKEN_BUDGET", 0),
+		AdminTokens:        getEnvAdminKeys("ADMIN_TOKENS"),
+		AdminHMACKeys:      getEnvAdminKeys("ADMIN_HMAC_KEYS"),
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		AccessTokenTTL:     getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:    getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		LLMProvider:        strings.ToLower(getEnv("LLM_PROVIDER", ProviderVolcano)),
+		MockCassetteDir:    getEnv("MOCK_CASSETTE_DIR", ""),
+		MockFailures:       getEnv("MOCK_FAILURES", ""),
+		MockLatencyMs:      getEnvInt64("MOCK_LATENCY_MS", 0),
+	}
+
+	switch cfg.LLMProvider {
+	case ProviderMock:
+		log.Println("🧪 使用模拟大模型（LLM_PROVIDER=mock），不会调用真实接口")
+	case ProviderVolcano:
+		if cfg.VolcanoAPIKey == "" {
+			log.Fatal("VOLCANO_API_KEY 环境变量未设置（离线开发可设置 LLM_PROVIDER=mock）")
+		}
+	default:
+		log.Fatalf("LLM_PROVIDER 无效: %s，可选 volcano、mock", cfg.LLMProvider)
 	}
 
-	if cfg.VolcanoAPIKey == "" {
-		log.Fatal("VOLCANO_API_KEY 环境变量未设置")
+	if cfg.JWTSecret != "" && len(cfg.JWTSecret) < 32 {
+		log.Println("⚠️  JWT_SECRET 少于 32 个字符，建议使用更长的随机字符串")
 	}
 
+	profiles, err := loadGenerationProfiles(getEnv("GENERATION_CONFIG", ""), getEnvRawList("MODEL_CHAIN"), models.GetAllLearningTypes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Profiles = profiles
+
 	return cfg
 }
 
@@ -41,4 +110,106 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvList 读取逗号分隔的列表，统一转为小写并去掉空项
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range getEnvRawList(key) {
+		result = append(result, strings.ToLower(item))
+	}
+	return result
+}
+
+// getEnvListDefault 与 getEnvList 相同，未设置时使用默认值，设置为 none 表示空列表
+func getEnvListDefault(key string, defaultValue []string) []string {
+	if os.Getenv(key) == "" {
+		return defaultValue
+	}
+	list := getEnvList(key)
+	if len(list) == 1 && list[0] == "none" {
+		return nil
+	}
+	return list
+}
+
+// getEnvRawList 读取逗号分隔的列表，保留大小写，用于模型名、密钥等区分大小写的配置
+func getEnvRawList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getEnvAdminKeys(key string) []AdminKey {
+	var result []AdminKey
+	for _, item := range getEnvRawList(key) {
+		name, secret, ok := strings.Cut(item, ":")
+		if !ok {
+			name, secret = "admin", item
+		}
+		name, secret = strings.TrimSpace(name), strings.TrimSpace(secret)
+		if secret == "" {
+			log.Printf("环境变量 %s 中的凭据 %s 缺少密钥，已忽略", key, name)
+			continue
+		}
+		result = append(result, AdminKey{Name: name, Secret: secret})
+	}
+	return result
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("环境变量 %s 不是有效的整数，使用默认值 %d", key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("环境变量 %s 不是有效的时长，使用默认值 %s", key, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// UsersEnabled 表示是否配置了 JWT 密钥，决定是否注册用户相关接口
+func (c *Config) UsersEnabled() bool {
+	return c.JWTSecret != ""
+}
+
+// AdminEnabled 表示是否配置了管理员凭据，决定是否注册 /admin 接口
+func (c *Config) AdminEnabled() bool {
+	return len(c.AdminTokens) > 0 || len(c.AdminHMACKeys) > 0
+}
+
+func (c *Config) IsCorpusMode(learningType string) bool {
+	return containsType(c.CorpusModeTypes, learningType)
+}
+
+func (c *Config) IsAutoTheme(learningType string) bool {
+	return containsType(c.AutoThemeTypes, learningType)
+}
+
+func containsType(types []string, learningType string) bool {
+	for _, t := range types {
+		if t == strings.ToLower(learningType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CorpusTier 是回退链中代表本地语料的特殊模型名
+const CorpusTier = "corpus"
+
+// OverrideTier 标记直接采用管理员预设内容、没有经过模型的记录
+const OverrideTier = "override"
+
+const (
+	DefaultModel       = "doubao-1.5-thinking-pro-250415"
+	DefaultTemperature = 0.7
+	DefaultMaxTokens   = 1500
+)
+
+// ModelTier 是回退链中的一级，未填写的参数使用默认值
+type ModelTier struct {
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+func (t ModelTier) IsCorpus() bool {
+	return strings.EqualFold(t.Model, CorpusTier)
+}
+
+func (t ModelTier) GetTemperature() float64 {
+	if t.Temperature == nil {
+		return DefaultTemperature
+	}
+	return *t.Temperature
+}
+
+func (t ModelTier) GetMaxTokens() int {
+	if t.MaxTokens <= 0 {
+		return DefaultMaxTokens
+	}
+	return t.MaxTokens
+}
+
+// LearnedPlaceholder 是旧版自定义提示词中插入已学内容的占位符。已学内容只作为数据放在用户消息中，
+// 系统提示词不再接受这个占位符
+const LearnedPlaceholder = "{{learned}}"
+
+// ValidateSystemPrompt 检查自定义生成提示词，包含 LearnedPlaceholder 时返回错误
+func ValidateSystemPrompt(prompt string) error {
+	if strings.Contains(prompt, LearnedPlaceholder) {
+		return fmt.Errorf("system_prompt 不能包含 %s，已学内容会以 JSON 数据放在用户消息的 learned 列表中", LearnedPlaceholder)
+	}
+	return nil
+}
+
+// PromptVariant 是提示词 A/B 实验中的一个版本，按 Weight 的比例分配生成请求。
+// SystemPrompt 为空表示使用内置提示词（或 profile 的 system_prompt）。
+type PromptVariant struct {
+	Name         string `json:"name"`
+	Weight       int    `json:"weight"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+}
+
+// GenerationProfile 是一种学习类型的生成配置。
+// Chain 按顺序尝试，调用失败、解析失败、内容重复或出处存疑时进入下一级。
+type GenerationProfile struct {
+	// 覆盖内置的生成提示词，需给出该类型的 JSON 输出格式；已学内容由用户消息提供
+	SystemPrompt string          `json:"system_prompt,omitempty"`
+	Chain        []ModelTier     `json:"chain,omitempty"`
+	Variants     []PromptVariant `json:"variants,omitempty"`
+}
+
+// Variant 按名称查找实验版本
+func (p GenerationProfile) Variant(name string) (PromptVariant, bool) {
+	for _, v := range p.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return PromptVariant{}, false
+}
+
+// ModelTiers 返回回退链中除本地语料以外的模型
+func (p GenerationProfile) ModelTiers() []ModelTier {
+	var tiers []ModelTier
+	for _, tier := range p.Chain {
+		if !tier.IsCorpus() {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+// PrimaryTier 返回回退链中的第一个模型，释义和出处复核都使用它
+func (p GenerationProfile) PrimaryTier() ModelTier {
+	if tiers := p.ModelTiers(); len(tiers) > 0 {
+		return tiers[0]
+	}
+	return ModelTier{Model: DefaultModel}
+}
+
+// generationFile 是 GENERATION_CONFIG 指向的 JSON 文件格式
+type generationFile struct {
+	Default GenerationProfile            `json:"default"`
+	Types   map[string]GenerationProfile `json:"types"`
+}
+
+// loadGenerationProfiles 合并配置文件和 MODEL_CHAIN，各类型未配置的项沿用默认配置
+func loadGenerationProfiles(path string, chain []string, learningTypes []string) (map[string]GenerationProfile, error) {
+	file := generationFile{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取生成配置失败: %v", err)
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析生成配置失败: %v", err)
+		}
+	}
+
+	if len(file.Default.Chain) == 0 {
+		for _, model := range chain {
+			file.Default.Chain = append(file.Default.Chain, ModelTier{Model: model})
+		}
+	}
+	if len(file.Default.Chain) == 0 {
+		file.Default.Chain = []ModelTier{{Model: DefaultModel}, {Model: CorpusTier}}
+	}
+
+	profiles := make(map[string]GenerationProfile)
+	for name, profile := range file.Types {
+		name = strings.ToLower(name)
+		if !contains(learningTypes, name) {
+			return nil, fmt.Errorf("生成配置中的学习类型无效: %s", name)
+		}
+		profiles[name] = profile
+	}
+
+	for _, learningType := range learningTypes {
+		profile := profiles[learningType]
+		if profile.SystemPrompt == "" {
+			profile.SystemPrompt = file.Default.SystemPrompt
+		}
+		if len(profile.Chain) == 0 {
+			profile.Chain = file.Default.Chain
+		}
+		if len(profile.Variants) == 0 {
+			profile.Variants = file.Default.Variants
+		}
+		for i, tier := range profile.Chain {
+			if strings.TrimSpace(tier.Model) == "" {
+				return nil, fmt.Errorf("%s 回退链第 %d 级缺少 model", learningType, i+1)
+			}
+		}
+		if err := ValidateSystemPrompt(profile.SystemPrompt); err != nil {
+			return nil, fmt.Errorf("%s 生成配置无效: %v", learningType, err)
+		}
+		if err := validateVariants(profile.Variants); err != nil {
+			return nil, fmt.Errorf("%s 提示词实验配置无效: %v", learningType, err)
+		}
+		profiles[learningType] = profile
+	}
+
+	return profiles, nil
+}
+
+func validateVariants(variants []PromptVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	total := 0
+	for _, v := range variants {
+		if strings.TrimSpace(v.Name) == "" {
+			return fmt.Errorf("版本缺少 name")
+		}
+		if names[v.Name] {
+			return fmt.Errorf("版本名重复: %s", v.Name)
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("版本 %s 的权重不能为负", v.Name)
+		}
+		if err := ValidateSystemPrompt(v.SystemPrompt); err != nil {
+			return fmt.Errorf("版本 %s 的 %v", v.Name, err)
+		}
+		names[v.Name] = true
+		total += v.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("各版本权重之和必须大于 0")
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Profile 返回指定学习类型的生成配置
+func (c *Config) Profile(learningType string) GenerationProfile {
+	if profile, ok := c.Profiles[strings.ToLower(learningType)]; ok {
+		return profile
+	}
+	return GenerationProfile{Chain: []ModelTier{{Model: DefaultModel}, {Model: CorpusTier}}}
+}
@@ -0,0 +1,54 @@
+package corpus
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var workPattern = regexp.MustCompile(`《([^》]+)》`)
+
+// Attribution 是从内容中解析出的出处标注，例如“—— 唐 李白 《静夜思》”
+type Attribution struct {
+	Text    string
+	Dynasty string
+	Author  string
+	Work    string
+}
+
+// Book 返回出处中的典籍名，例如“黄帝内经·素问·上古天真论”记为“黄帝内经”
+func (a *Attribution) Book() string {
+	return strings.TrimSpace(strings.SplitN(a.Work, "·", 2)[0])
+}
+
+// ParseAttribution 按“原文—— 朝代 作者 《作品》”的格式拆分内容，没有出处时 ok 为 false
+func ParseAttribution(content string) (*Attribution, bool) {
+	parts := strings.SplitN(content, "——", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	attr := &Attribution{Text: strings.TrimSpace(parts[0])}
+	rest := parts[1]
+	if m := workPattern.FindStringSubmatch(rest); m != nil {
+		attr.Work = strings.TrimSpace(m[1])
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+
+	fields := strings.FieldsFunc(rest, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("·・，,、", r)
+	})
+	switch len(fields) {
+	case 0:
+	case 1:
+		attr.Author = fields[0]
+	default:
+		attr.Dynasty = fields[0]
+		attr.Author = fields[1]
+	}
+
+	if attr.Text == "" || (attr.Author == "" && attr.Work == "") {
+		return nil, false
+	}
+	return attr, true
+}
@@ -0,0 +1,259 @@
+package corpus
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// 本地语料随程序一起编译，每种学习类型对应 data 目录下的一个 JSON 或 CSV 文件
+//
+//go:embed data/*.json data/*.csv
+var dataFS embed.FS
+
+// Item 是一条经过核对的本地语料
+type Item struct {
+	ID             string   `json:"id"`
+	Type           string   `json:"type"`
+	Text           string   `json:"text"`
+	Title          string   `json:"title,omitempty"`
+	Author         string   `json:"author,omitempty"`
+	Dynasty        string   `json:"dynasty,omitempty"`
+	Source         string   `json:"source,omitempty"`
+	Interpretation string   `json:"interpretation,omitempty"`
+	KeyWords       []string `json:"key_words,omitempty"`
+}
+
+// Content 返回保存到学习记录中的完整内容，格式与模型生成的内容一致
+func (it *Item) Content() string {
+	switch it.Type {
+	case "chinese":
+		return fmt.Sprintf("%s—— %s %s 《%s》", it.Text, it.Dynasty, it.Author, it.Title)
+	case "tcm":
+		return fmt.Sprintf("%s—— 《%s·%s》", it.Text, it.Source, it.Title)
+	default:
+		return it.Text
+	}
+}
+
+// Complete 表示该条语料自带释义和关键词，无需模型补全即可直接使用
+func (it *Item) Complete() bool {
+	return strings.TrimSpace(it.Interpretation) != "" && len(it.KeyWords) > 0
+}
+
+var (
+	itemsByType = make(map[string][]Item)
+	itemsByID   = make(map[string]*Item)
+)
+
+func init() {
+	entries, err := dataFS.ReadDir("data")
+	if err != nil {
+		log.Fatalf("读取本地语料目录失败: %v", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		learningType := strings.TrimSuffix(name, path.Ext(name))
+
+		items, err := loadFile(path.Join("data", name), learningType)
+		if err != nil {
+			log.Fatalf("加载本地语料 %s 失败: %v", name, err)
+		}
+		itemsByType[learningType] = append(itemsByType[learningType], items...)
+	}
+
+	for learningType, items := range itemsByType {
+		for i := range items {
+			if _, exists := itemsByID[items[i].ID]; exists {
+				log.Fatalf("本地语料ID重复: %s", items[i].ID)
+			}
+			itemsByID[items[i].ID] = &itemsByType[learningType][i]
+		}
+	}
+}
+
+func loadFile(name string, learningType string) ([]Item, error) {
+	f, err := dataFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []Item
+	switch path.Ext(name) {
+	case ".json":
+		items, err = loadJSON(f)
+	case ".csv":
+		items, err = loadCSV(f)
+	default:
+		return nil, fmt.Errorf("不支持的语料格式: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Type = learningType
+		if items[i].ID == "" || strings.TrimSpace(items[i].Text) == "" {
+			return nil, fmt.Errorf("第 %d 条语料缺少 id 或 text", i+1)
+		}
+	}
+	return items, nil
+}
+
+func loadJSON(r io.Reader) ([]Item, error) {
+	var items []Item
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+	return items, nil
+}
+
+// loadCSV 读取带表头的 CSV，key_words 列内多个关键词用 | 分隔
+func loadCSV(r io.Reader) ([]Item, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV失败: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	get := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var items []Item
+	for _, row := range rows[1:] {
+		item := Item{
+			ID:             get(row, "id"),
+			Text:           get(row, "text"),
+			Title:          get(row, "title"),
+			Author:         get(row, "author"),
+			Dynasty:        get(row, "dynasty"),
+			Source:         get(row, "source"),
+			Interpretation: get(row, "interpretation"),
+		}
+		for _, kw := range strings.Split(get(row, "key_words"), "|") {
+			if kw = strings.TrimSpace(kw); kw != "" {
+				item.KeyWords = append(item.KeyWords, kw)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Items 返回指定类型的全部语料，顺序与数据文件一致
+func Items(learningType string) []Item {
+	return itemsByType[strings.ToLower(learningType)]
+}
+
+func Get(id string) (*Item, bool) {
+	item, ok := itemsByID[id]
+	return item, ok
+}
+
+// Unlearned 按语料顺序返回尚未学过的条目。
+// 已学内容可能来自模型生成，格式不完全一致，因此已学内容包含原文、
+// 或原文包含已学内容的正文部分（去掉“——”后的出处）都视为学过。
+func Unlearned(learningType string, learned []string) []Item {
+	var result []Item
+	for _, item := range Items(learningType) {
+		if !IsLearned(item, learned) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func IsLearned(item Item, learned []string) bool {
+	return ContainsLearned(item.Text, learned)
+}
+
+// ContainsLearned 判断一段原文是否与已学内容重复，规则与 Unlearned 相同
+func ContainsLearned(text string, learned []string) bool {
+	text = normalize(strings.SplitN(text, "——", 2)[0])
+	if text == "" {
+		return false
+	}
+	for _, content := range learned {
+		normalized := normalize(content)
+		if strings.Contains(normalized, text) {
+			return true
+		}
+		body := strings.TrimSpace(strings.SplitN(normalized, "——", 2)[0])
+		if body != "" && strings.Contains(text, body) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// FindByText 在指定类型的语料中查找原文相符的条目，作为出处核验的参考。
+// 生成内容往往只截取其中一两句，因此双向包含都算相符。
+func FindByText(learningType string, text string) (*Item, bool) {
+	needle := normalize(text)
+	if utf8.RuneCountInString(needle) < 5 {
+		return nil, false
+	}
+	items := Items(learningType)
+	for i := range items {
+		itemText := normalize(items[i].Text)
+		if strings.Contains(itemText, needle) || strings.Contains(needle, itemText) {
+			return &items[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindByTitle 查找指定类型中作品名相同的条目
+func FindByTitle(learningType string, title string) []Item {
+	title = baseTitle(title)
+	if title == "" {
+		return nil
+	}
+	var result []Item
+	for _, item := range Items(learningType) {
+		if baseTitle(item.Title) == title {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// baseTitle 去掉组诗序号等后缀，例如“行路难·其一”记为“行路难”
+func baseTitle(title string) string {
+	title = strings.Trim(strings.TrimSpace(title), "《》")
+	if i := strings.Index(title, "·其"); i >= 0 {
+		title = title[:i]
+	}
+	return title
+}
+
+// SameTitle 判断两个作品名是否指同一作品
+func SameTitle(a, b string) bool {
+	a, b = baseTitle(a), baseTitle(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.Contains(a, b) || strings.Contains(b, a)
+}
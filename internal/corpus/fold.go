@@ -0,0 +1,25 @@
+package corpus
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Fold 把全角字母数字和符号转为半角、统一小写，并去掉空白和标点，
+// 用于比较两段文字是否相同，例如判分和合并重复的词语
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xfee0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
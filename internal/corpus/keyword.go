@@ -0,0 +1,26 @@
+package corpus
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Keyword 是“词语: 释义”格式的关键词
+type Keyword struct {
+	Word    string
+	Meaning string
+}
+
+// ParseKeyword 拆分“词语: 释义”，全角冒号同样可以
+func ParseKeyword(s string) (Keyword, bool) {
+	i := strings.IndexAny(s, ":：")
+	if i < 0 {
+		return Keyword{}, false
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	kw := Keyword{Word: strings.TrimSpace(s[:i]), Meaning: strings.TrimSpace(s[i+size:])}
+	if kw.Word == "" || kw.Meaning == "" {
+		return Keyword{}, false
+	}
+	return kw, true
+}
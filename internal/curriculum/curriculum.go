@@ -0,0 +1,137 @@
+package curriculum
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// 内置学习路径随程序一起编译，每条路径对应 data 目录下的一个 JSON 文件
+//
+//go:embed data/*.json
+var dataFS embed.FS
+
+// Unit 是学习路径中的一个单元：给出原文（Text）时模型只负责讲解原文，
+// 否则按主题（Topic）请模型挑选内容
+type Unit struct {
+	Title  string `json:"title"`
+	Topic  string `json:"topic,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// Content 返回保存到学习记录中的完整内容，格式与本地语料一致
+func (u *Unit) Content() string {
+	if u.Source == "" {
+		return u.Text
+	}
+	return fmt.Sprintf("%s—— 《%s》", u.Text, u.Source)
+}
+
+// Path 是一条按顺序学习的路径，只适用于一种学习类型
+type Path struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Units       []Unit `json:"units"`
+}
+
+// Unit 返回第 seq 个单元（从 1 开始），超出范围时返回 nil
+func (p *Path) Unit(seq int) *Unit {
+	if seq < 1 || seq > len(p.Units) {
+		return nil
+	}
+	return &p.Units[seq-1]
+}
+
+// Target 描述第 seq 个单元，写入生成提示词，让模型围绕该单元挑选内容
+func (p *Path) Target(seq int) string {
+	unit := p.Unit(seq)
+	if unit == nil {
+		return ""
+	}
+	target := fmt.Sprintf("%s 第 %d 单元「%s」", p.Title, seq, unit.Title)
+	if unit.Topic != "" {
+		target += "：" + unit.Topic
+	}
+	return target
+}
+
+var (
+	paths  []Path
+	byName = make(map[string]*Path)
+)
+
+func init() {
+	entries, err := dataFS.ReadDir("data")
+	if err != nil {
+		log.Fatalf("读取学习路径目录失败: %v", err)
+	}
+
+	for _, entry := range entries {
+		p, err := loadFile(path.Join("data", entry.Name()))
+		if err != nil {
+			log.Fatalf("加载学习路径 %s 失败: %v", entry.Name(), err)
+		}
+		paths = append(paths, *p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+
+	for i := range paths {
+		if _, exists := byName[paths[i].Name]; exists {
+			log.Fatalf("学习路径名称重复: %s", paths[i].Name)
+		}
+		byName[paths[i].Name] = &paths[i]
+	}
+}
+
+func loadFile(name string) (*Path, error) {
+	data, err := dataFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Path
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+	p.Type = strings.ToLower(p.Type)
+	if p.Name == "" || p.Type == "" || len(p.Units) == 0 {
+		return nil, fmt.Errorf("缺少 name、type 或 units")
+	}
+	for i, unit := range p.Units {
+		if unit.Title == "" || (strings.TrimSpace(unit.Text) == "" && strings.TrimSpace(unit.Topic) == "") {
+			return nil, fmt.Errorf("第 %d 个单元缺少 title，或 text 和 topic 都为空", i+1)
+		}
+	}
+	return &p, nil
+}
+
+// All 返回全部学习路径，按名称排序
+func All() []Path {
+	return paths
+}
+
+// ForType 返回指定类型的学习路径，type 为空时返回全部
+func ForType(learningType string) []Path {
+	if learningType == "" {
+		return paths
+	}
+	var result []Path
+	for _, p := range paths {
+		if p.Type == strings.ToLower(learningType) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func Get(name string) (*Path, bool) {
+	p, ok := byName[name]
+	return p, ok
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 管理接口使用的增删改查，能看到包括出处存疑在内的全部记录，每次变更都写入审计日志
+
+// ListRecords 分页列出学习记录，withDeleted 为 true 时包含已删除的记录
+func ListRecords(ctx context.Context, learningType string, withDeleted bool, limit, offset int) ([]models.LearningRecord, int64, error) {
+	var records []models.LearningRecord
+	var total int64
+
+	query := DB.WithContext(ctx).Model(&models.LearningRecord{})
+	if withDeleted {
+		query = query.Unscoped()
+	}
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计学习记录失败: %v", err)
+	}
+	if err := query.Order("date DESC, id DESC").Limit(limit).Offset(offset).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取学习记录失败: %v", err)
+	}
+	return records, total, nil
+}
+
+// GetRecord 按ID获取学习记录，不存在时返回 nil
+func GetRecord(ctx context.Context, id uint) (*models.LearningRecord, error) {
+	var record models.LearningRecord
+	if err := DB.WithContext(ctx).First(&record, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// CreateRecord 新建学习记录作为当天展示的版本，并计入已学习内容，避免之后再生成重复内容
+func CreateRecord(ctx context.Context, change Change, record *models.LearningRecord) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if record.Verification != models.VerificationDisputed {
+			if err := publishRecord(tx, change, record); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("创建学习记录失败: %v", err)
+		}
+		if err := markLearned(tx, change, record); err != nil {
+			return err
+		}
+		return recordAudit(tx, change, models.AuditCreate, models.EntityLearningRecord, record.ID, nil, record)
+	})
+}
+
+// UpdateRecord 保存修改后的记录，before 为修改前的快照。
+// 换了类型或日期时记录在新的一天重新编号；换了类型、日期或由存疑改为可展示时，记录成为当天展示的版本
+func UpdateRecord(ctx context.Context, change Change, before models.LearningRecord, record *models.LearningRecord) error {
+	beforeStart, _ := dayRange(before.Date)
+	recordStart, _ := dayRange(record.Date)
+	moved := record.Type != before.Type || !recordStart.Equal(beforeStart)
+	restored := before.Verification == models.VerificationDisputed && record.Verification != models.VerificationDisputed
+
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if moved {
+			record.Version = 0
+			record.Superseded = false
+		}
+		if (moved || restored) && record.Verification != models.VerificationDisputed {
+			if err := publishRecord(tx, change, record); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(record).Error; err != nil {
+			return fmt.Errorf("更新学习记录失败: %v", err)
+		}
+		if err := markLearned(tx, change, record); err != nil {
+			return err
+		}
+		return recordAudit(tx, change, models.AuditUpdate, models.EntityLearningRecord, record.ID, before, record)
+	})
+}
+
+// DeleteRecord 软删除学习记录，可通过审计日志恢复
+func DeleteRecord(ctx context.Context, change Change, record *models.LearningRecord) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.LearningRecord{}, record.ID).Error; err != nil {
+			return fmt.Errorf("删除学习记录失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditDelete, models.EntityLearningRecord, record.ID, record, nil)
+	})
+}
+
+// PinRecord 把记录固定到 record.Date 所在的那一天，作为当天展示的版本：当天其他版本
+// 转为旧版本，原先固定的记录取消固定。record 没有ID时新建；固定到原来那一天时更新原记录，
+// 固定到别的日期时新建一份副本，原记录保持不变
+func PinRecord(ctx context.Context, change Change, record *models.LearningRecord) error {
+	dayStart, _ := dayRange(record.Date)
+
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before interface{}
+		if record.ID != 0 {
+			var existing models.LearningRecord
+			if err := tx.First(&existing, record.ID).Error; err != nil {
+				return fmt.Errorf("获取学习记录失败: %v", err)
+			}
+			if existingStart, _ := dayRange(existing.Date); existingStart.Equal(dayStart) {
+				before = existing
+			} else {
+				record.ID = 0
+				record.Version = 0
+				record.CreatedAt, record.UpdatedAt = time.Time{}, time.Time{}
+			}
+		}
+
+		record.Pinned = true
+		record.Date = dayStart
+		if err := publishRecord(tx, change, record); err != nil {
+			return err
+		}
+		if err := tx.Save(record).Error; err != nil {
+			return fmt.Errorf("固定学习记录失败: %v", err)
+		}
+		if err := markLearned(tx, change, record); err != nil {
+			return err
+		}
+		return recordAudit(tx, change, models.AuditPin, models.EntityLearningRecord, record.ID, before, record)
+	})
+}
+
+// GetPinnedRecord 返回某一天固定的记录，没有时返回 nil
+func GetPinnedRecord(ctx context.Context, learningType string, day time.Time) (*models.LearningRecord, error) {
+	dayStart, dayEnd := dayRange(day)
+
+	var record models.LearningRecord
+	err := DB.WithContext(ctx).Where("type = ? AND pinned = ? AND date >= ? AND date < ?", learningType, true, dayStart, dayEnd).
+		First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取固定记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// markLearned 把对外展示的记录计入已学习内容，新增时记入审计日志
+func markLearned(tx *gorm.DB, change Change, record *models.LearningRecord) error {
+	if record.Verification == models.VerificationDisputed {
+		return nil
+	}
+	var existing models.LearnedContent
+	result := tx.Where("type = ? AND content = ?", record.Type, record.Content).
+		FirstOrCreate(&existing, models.LearnedContent{Type: record.Type, Content: record.Content})
+	if result.Error != nil {
+		return fmt.Errorf("保存已学习内容失败: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return recordAudit(tx, change, models.AuditCreate, models.EntityLearnedContent, existing.ID, nil, existing)
+	}
+	return nil
+}
+
+func ListLearned(ctx context.Context, learningType string, limit, offset int) ([]models.LearnedContent, int64, error) {
+	var contents []models.LearnedContent
+	var total int64
+
+	query := DB.WithContext(ctx).Model(&models.LearnedContent{})
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计已学习内容失败: %v", err)
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&contents).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取已学习内容失败: %v", err)
+	}
+	return contents, total, nil
+}
+
+// GetLearned 按ID获取已学习内容，不存在时返回 nil
+func GetLearned(ctx context.Context, id uint) (*models.LearnedContent, error) {
+	var content models.LearnedContent
+	if err := DB.WithContext(ctx).First(&content, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取已学习内容失败: %v", err)
+	}
+	return &content, nil
+}
+
+func CreateLearned(ctx context.Context, change Change, content *models.LearnedContent) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(content).Error; err != nil {
+			return fmt.Errorf("创建已学习内容失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditCreate, models.EntityLearnedContent, content.ID, nil, content)
+	})
+}
+
+func UpdateLearned(ctx context.Context, change Change, before models.LearnedContent, content *models.LearnedContent) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(content).Error; err != nil {
+			return fmt.Errorf("更新已学习内容失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditUpdate, models.EntityLearnedContent, content.ID, before, content)
+	})
+}
+
+func DeleteLearned(ctx context.Context, change Change, content *models.LearnedContent) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.LearnedContent{}, content.ID).Error; err != nil {
+			return fmt.Errorf("删除已学习内容失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditDelete, models.EntityLearnedContent, content.ID, content, nil)
+	})
+}
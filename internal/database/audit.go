@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"everyday-study-backend/internal/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Change 描述一次变更的操作人和原因，随变更写入审计日志
+type Change struct {
+	Actor  string
+	Reason string
+}
+
+var (
+	ErrNothingToRestore  = errors.New("该审计日志没有可恢复的快照")
+	ErrUnsupportedEntity = errors.New("不支持恢复该类型的数据")
+)
+
+// auditTriggers 让 audit_events 只能追加，任何修改或删除都会被数据库拒绝
+var auditTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`,
+	`CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`,
+}
+
+func protectAuditEvents(db *gorm.DB) error {
+	for _, stmt := range auditTriggers {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("创建审计日志触发器失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// recordAudit 在给定事务中追加一条审计日志，before、after 为 nil 时不记录对应快照
+func recordAudit(tx *gorm.DB, change Change, action, entity string, entityID uint, before, after interface{}) error {
+	actor := change.Actor
+	if actor == "" {
+		actor = models.ActorSystem
+	}
+	event := models.AuditEvent{
+		Actor:    actor,
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		Before:   snapshot(before),
+		After:    snapshot(after),
+		Reason:   change.Reason,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+	return nil
+}
+
+func snapshot(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func SaveAuditEvent(ctx context.Context, change Change, action, entity string, entityID uint, before, after interface{}) error {
+	return recordAudit(DB.WithContext(ctx), change, action, entity, entityID, before, after)
+}
+
+// ListAuditEvents 按时间倒序返回符合条件的审计日志
+func ListAuditEvents(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+
+	query := DB.WithContext(ctx).Model(&models.AuditEvent{})
+	if q.Entity != "" {
+		query = query.Where("entity = ?", q.Entity)
+	}
+	if q.EntityID != 0 {
+		query = query.Where("entity_id = ?", q.EntityID)
+	}
+	if q.Actor != "" {
+		query = query.Where("actor = ?", q.Actor)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if !q.Since.IsZero() {
+		query = query.Where("created_at >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		query = query.Where("created_at < ?", q.Until)
+	}
+
+	if err := query.Order("id DESC").Limit(q.Limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("获取审计日志失败: %v", err)
+	}
+	return events, nil
+}
+
+// GetAuditEvent 按ID获取审计日志，不存在时返回 nil
+func GetAuditEvent(ctx context.Context, id uint) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	if err := DB.WithContext(ctx).First(&event, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取审计日志失败: %v", err)
+	}
+	return &event, nil
+}
+
+// RestoreFromAudit 把数据恢复为审计日志中的快照（useAfter 为 false 时恢复变更前的快照）。
+// 数据已被删除时按原ID重新创建；恢复的学习记录成为同类型当天对外展示的版本。
+func RestoreFromAudit(ctx context.Context, change Change, event *models.AuditEvent, useAfter bool) (interface{}, error) {
+	data := event.Before
+	if useAfter {
+		data = event.After
+	}
+	if data == "" {
+		return nil, ErrNothingToRestore
+	}
+
+	switch event.Entity {
+	case models.EntityLearningRecord:
+		var record models.LearningRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("解析快照失败: %v", err)
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return restoreRecord(tx, change, &record)
+		})
+		return &record, err
+	case models.EntityLearnedContent:
+		var content models.LearnedContent
+		if err := json.Unmarshal([]byte(data), &content); err != nil {
+			return nil, fmt.Errorf("解析快照失败: %v", err)
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return restoreRow(tx, change, event.Entity, content.ID, &models.LearnedContent{}, &content)
+		})
+		return &content, err
+	case models.EntityOverride:
+		var override models.Override
+		if err := json.Unmarshal([]byte(data), &override); err != nil {
+			return nil, fmt.Errorf("解析快照失败: %v", err)
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return restoreRow(tx, change, event.Entity, override.ID, &models.Override{}, &override)
+		})
+		return &override, err
+	case models.EntityCurriculum:
+		var progress models.CurriculumProgress
+		if err := json.Unmarshal([]byte(data), &progress); err != nil {
+			return nil, fmt.Errorf("解析快照失败: %v", err)
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// 同一类型只能启用一条路径
+			if progress.Active {
+				if err := deactivateCurricula(tx, change, progress.Type, progress.Path); err != nil {
+					return err
+				}
+			}
+			return restoreRow(tx, change, event.Entity, progress.ID, &models.CurriculumProgress{}, &progress)
+		})
+		return &progress, err
+	default:
+		return nil, ErrUnsupportedEntity
+	}
+}
+
+func restoreRecord(tx *gorm.DB, change Change, record *models.LearningRecord) error {
+	// 软删除的记录也要查到，恢复时清除删除标记
+	var before interface{}
+	var current models.LearningRecord
+	err := tx.Unscoped().First(&current, record.ID).Error
+	switch {
+	case err == nil:
+		before = current
+	case err != gorm.ErrRecordNotFound:
+		return fmt.Errorf("获取学习记录失败: %v", err)
+	}
+
+	record.DeletedAt = gorm.DeletedAt{}
+	if record.Verification != models.VerificationDisputed {
+		if err := publishRecord(tx, change, record); err != nil {
+			return err
+		}
+	}
+
+	if before != nil {
+		err = tx.Unscoped().Save(record).Error
+	} else {
+		err = tx.Create(record).Error
+	}
+	if err != nil {
+		return fmt.Errorf("恢复学习记录失败: %v", err)
+	}
+	if err := markLearned(tx, change, record); err != nil {
+		return err
+	}
+	return recordAudit(tx, change, models.AuditRestore, models.EntityLearningRecord, record.ID, before, record)
+}
+
+// restoreRow 把没有版本和删除标记的数据恢复为快照 row，current 为同类型的空值，用于读取恢复前的数据
+func restoreRow(tx *gorm.DB, change Change, entity string, id uint, current interface{}, row interface{}) error {
+	var before interface{}
+	err := tx.First(current, id).Error
+	switch {
+	case err == nil:
+		before = current
+		err = tx.Save(row).Error
+	case err == gorm.ErrRecordNotFound:
+		err = tx.Create(row).Error
+	}
+	if err != nil {
+		return fmt.Errorf("恢复数据失败: %v", err)
+	}
+	return recordAudit(tx, change, models.AuditRestore, entity, id, before, row)
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ListCurriculumProgress 返回已有的学习路径进度，按路径名索引；从未启用过的路径没有进度
+func ListCurriculumProgress(ctx context.Context) (map[string]models.CurriculumProgress, error) {
+	var progress []models.CurriculumProgress
+	if err := DB.WithContext(ctx).Find(&progress).Error; err != nil {
+		return nil, fmt.Errorf("获取学习路径进度失败: %v", err)
+	}
+
+	result := make(map[string]models.CurriculumProgress, len(progress))
+	for _, p := range progress {
+		result[p.Path] = p
+	}
+	return result, nil
+}
+
+// GetActiveCurriculum 返回该类型启用的学习路径进度，没有时返回 nil
+func GetActiveCurriculum(ctx context.Context, learningType string) (*models.CurriculumProgress, error) {
+	var progress models.CurriculumProgress
+	err := DB.WithContext(ctx).Where("type = ? AND active = ?", learningType, true).First(&progress).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习路径进度失败: %v", err)
+	}
+	return &progress, nil
+}
+
+// ActivateCurriculum 启用该类型的学习路径并停用同类型的其他路径，path 为空时只停用。
+// 路径原有的进度保留，重新启用时从上次的位置继续
+func ActivateCurriculum(ctx context.Context, change Change, learningType, path string) (*models.CurriculumProgress, error) {
+	var progress *models.CurriculumProgress
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deactivateCurricula(tx, change, learningType, path); err != nil {
+			return err
+		}
+		if path == "" {
+			return nil
+		}
+
+		p, before, err := loadProgress(tx, learningType, path)
+		if err != nil {
+			return err
+		}
+		p.Active = true
+		progress = &p
+		return saveProgress(tx, change, before, progress)
+	})
+	return progress, err
+}
+
+// SetCurriculumPosition 把学习路径的进度设为已学完 position 个单元，下次生成从下一个单元开始
+func SetCurriculumPosition(ctx context.Context, change Change, learningType, path string, position int) (*models.CurriculumProgress, error) {
+	var progress models.CurriculumProgress
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		p, before, err := loadProgress(tx, learningType, path)
+		if err != nil {
+			return err
+		}
+		p.Position = position
+		p.LastDate = ""
+		progress = p
+		return saveProgress(tx, change, before, &progress)
+	})
+	return &progress, err
+}
+
+// advanceCurriculum 在保存学习路径单元的内容后推进进度；同一天重新生成同一单元时只更新日期
+func advanceCurriculum(tx *gorm.DB, change Change, record *models.LearningRecord) error {
+	p, before, err := loadProgress(tx, record.Type, record.Curriculum)
+	if err != nil {
+		return err
+	}
+	if record.Unit < p.Position {
+		return nil
+	}
+	p.Position = record.Unit
+	p.LastDate = record.Date.Format("2006-01-02")
+	return saveProgress(tx, change, before, &p)
+}
+
+// deactivateCurricula 停用该类型除 except 外的所有启用路径
+func deactivateCurricula(tx *gorm.DB, change Change, learningType, except string) error {
+	var active []models.CurriculumProgress
+	if err := tx.Where("type = ? AND active = ? AND path <> ?", learningType, true, except).Find(&active).Error; err != nil {
+		return fmt.Errorf("获取学习路径进度失败: %v", err)
+	}
+	for i := range active {
+		before := active[i]
+		active[i].Active = false
+		if err := saveProgress(tx, change, &before, &active[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadProgress 读取学习路径进度，没有时返回新的进度，before 为 nil
+func loadProgress(tx *gorm.DB, learningType, path string) (models.CurriculumProgress, *models.CurriculumProgress, error) {
+	var p models.CurriculumProgress
+	err := tx.Where("path = ?", path).First(&p).Error
+	switch {
+	case err == nil:
+		before := p
+		return p, &before, nil
+	case err == gorm.ErrRecordNotFound:
+		return models.CurriculumProgress{Path: path, Type: learningType}, nil, nil
+	default:
+		return p, nil, fmt.Errorf("获取学习路径进度失败: %v", err)
+	}
+}
+
+func saveProgress(tx *gorm.DB, change Change, before, progress *models.CurriculumProgress) error {
+	action := models.AuditUpdate
+	if before == nil {
+		action = models.AuditCreate
+	}
+	if err := tx.Save(progress).Error; err != nil {
+		return fmt.Errorf("保存学习路径进度失败: %v", err)
+	}
+
+	var beforeSnapshot interface{}
+	if before != nil {
+		beforeSnapshot = before
+	}
+	return recordAudit(tx, change, action, models.EntityCurriculum, progress.ID, beforeSnapshot, progress)
+}
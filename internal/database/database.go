@@ -1,9 +1,11 @@
 package database
 
 import (
+	"context"
 	"everyday-study-backend/internal/config"
 	"everyday-study-backend/internal/models"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -24,22 +26,71 @@ func Init(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("连接数据库失败: %v", err)
 	}
 
+	// 版本号列首次加入时需要为已有记录补上版本号
+	hasVersion := DB.Migrator().HasColumn(&models.LearningRecord{}, "version")
+
 	err = DB.AutoMigrate(
 		&models.LearningRecord{},
 		&models.LearnedContent{},
+		&models.GenerationJob{},
+		&models.GenerationAttempt{},
+		&models.Feedback{},
+		&models.PromptPromotion{},
+		&models.AuditEvent{},
+		&models.Override{},
+		&models.CurriculumProgress{},
+		&models.User{},
+		&models.RefreshToken{},
+		&models.UserRecord{},
+		&models.ReviewState{},
+		&models.Quiz{},
+		&models.QuizResult{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("数据库迁移失败: %v", err)
 	}
+	if err := protectAuditEvents(DB); err != nil {
+		return nil, err
+	}
+	if err := uniqueActiveJobs(DB); err != nil {
+		return nil, err
+	}
+	if err := uniqueFeedbackVoters(DB); err != nil {
+		return nil, err
+	}
+	// 练习题改为按记录版本保存，去掉早期每条记录只保存一份题目的唯一索引
+	if DB.Migrator().HasIndex(&models.Quiz{}, "idx_quizzes_record_id") {
+		if err := DB.Migrator().DropIndex(&models.Quiz{}, "idx_quizzes_record_id"); err != nil {
+			return nil, fmt.Errorf("迁移练习题索引失败: %v", err)
+		}
+	}
+	if !hasVersion {
+		if err := backfillVersions(DB); err != nil {
+			return nil, err
+		}
+	}
 
 	fmt.Println("✅ 数据库初始化完成")
 	return DB, nil
 }
 
-func GetLearnedContent(learningType string) ([]string, error) {
+// PublicRecords 过滤掉出处存疑的记录、被替换的旧版本和固定到未来日期的记录，所有对外接口的查询都应使用
+func PublicRecords(db *gorm.DB) *gorm.DB {
+	now := time.Now()
+	tomorrowStart := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	return db.Where("verification IS NULL OR verification <> ?", models.VerificationDisputed).
+		Where("superseded = ? AND date < ?", false, tomorrowStart)
+}
+
+// isUniqueViolation 判断写入是否因违反唯一约束而失败，用于把并发下的重复写入转换成业务错误
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func GetLearnedContent(ctx context.Context, learningType string) ([]string, error) {
 	var contents []models.LearnedContent
 	
-	err := DB.Where("type = ?", learningType).
+	err := DB.WithContext(ctx).Where("type = ?", learningType).
 		Order("created_at").
 		Find(&contents).Error
 	
@@ -56,7 +107,7 @@ func GetLearnedContent(learningType string) ([]string, error) {
 }
 
 // 修复：获取今日学习记录的函数
-func GetTodayLearningRecord(learningType string) (*models.LearningRecord, error) {
+func GetTodayLearningRecord(ctx context.Context, learningType string) (*models.LearningRecord, error) {
 	var record models.LearningRecord
 	
 	// 获取今天的开始和结束时间（本地时间）
@@ -69,8 +120,9 @@ func GetTodayLearningRecord(learningType string) (*models.LearningRecord, error)
 		todayStart.Format("2006-01-02 15:04:05"), 
 		todayEnd.Format("2006-01-02 15:04:05"))
 	
-	err := DB.Where("type = ? AND date >= ? AND date < ?", learningType, todayStart, todayEnd).
-		Order("date DESC").
+	err := DB.WithContext(ctx).Scopes(PublicRecords).
+		Where("type = ? AND date >= ? AND date < ?", learningType, todayStart, todayEnd).
+		Order("pinned DESC, date DESC").
 		First(&record).Error
 		
 	if err != nil {
@@ -90,80 +142,75 @@ func GetTodayLearningRecord(learningType string) (*models.LearningRecord, error)
 }
 
 // 修复：保存学习记录的函数
-func SaveLearningRecord(learningType string, content models.LearningContent) (*models.LearningRecord, error) {
+// 今天已有的记录转为旧版本保留，新记录作为下一个版本对外展示；变更都写入审计日志，
+// 操作人和原因来自 change
+func SaveLearningRecord(ctx context.Context, change Change, learningType string, content models.LearningContent) (*models.LearningRecord, error) {
 	if errors := content.Validate(); len(errors) > 0 {
 		return nil, fmt.Errorf("数据验证失败: %v", errors)
 	}
 
 	now := time.Now()
-	// 获取今天的开始和结束时间
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	todayEnd := todayStart.Add(24 * time.Hour)
 
 	fmt.Printf("💾 保存学习记录 - 类型: %s, 时间: %s\n", 
 		learningType, now.Format("2006-01-02 15:04:05"))
 
-	tx := DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 删除今天同类型的旧记录（如果有的话）
-	var deleteCount int64
-	result := tx.Where("type = ? AND date >= ? AND date < ?", learningType, todayStart, todayEnd).
-		Delete(&models.LearningRecord{})
-	if result.Error != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("删除旧记录失败: %v", result.Error)
-	}
-	deleteCount = result.RowsAffected
-	
-	if deleteCount > 0 {
-		fmt.Printf("🗑️  删除了 %d 条今日旧记录\n", deleteCount)
-	}
-
-	// 创建新记录
 	record := models.LearningRecord{
-		Type:           learningType,
-		Content:        content.Content,
-		Interpretation: content.Interpretation,
-		KeyWords:       content.FormatKeyWords(),
-		Date:           now, // 使用当前完整时间
+		Type:             learningType,
+		Content:          content.Content,
+		Interpretation:   content.Interpretation,
+		KeyWords:         content.FormatKeyWords(),
+		SourceID:         content.SourceID,
+		Verification:     content.Verification,
+		VerificationNote: content.VerificationNote,
+		AttemptID:        content.AttemptID,
+		Tier:             content.Tier,
+		Variant:          content.Variant,
+		Theme:            content.Theme,
+		OverrideID:       content.OverrideID,
+		Curriculum:       content.Curriculum,
+		Unit:             content.Unit,
+		Date:             now, // 使用当前完整时间
 	}
 
-	if err := tx.Create(&record).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("保存学习记录失败: %v", err)
-	}
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 出处存疑的记录不对外展示，既不替换已有内容，也不会被新内容替换，留作核查；
+		// 管理员固定的记录也不会被替换
+		if content.Verification != models.VerificationDisputed {
+			if err := publishRecord(tx, change, &record); err != nil {
+				return err
+			}
+		}
 
-	fmt.Printf("✅ 学习记录已保存，ID: %d\n", record.ID)
+		// 创建新记录
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("保存学习记录失败: %v", err)
+		}
+		if err := recordAudit(tx, change, models.AuditCreate, models.EntityLearningRecord, record.ID, nil, record); err != nil {
+			return err
+		}
 
-	// 保存到已学习内容表（防重复）
-	learnedContent := models.LearnedContent{
-		Type:    learningType,
-		Content: content.Content,
-	}
-	
-	var existing models.LearnedContent
-	if err := tx.Where("type = ? AND content = ?", learningType, content.Content).
-		FirstOrCreate(&existing, learnedContent).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("保存已学习内容失败: %v", err)
-	}
+		// 学习路径的单元发布后推进进度，存疑内容不算学过
+		if record.Curriculum != "" && content.Verification != models.VerificationDisputed {
+			if err := advanceCurriculum(tx, change, &record); err != nil {
+				return err
+			}
+		}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("事务提交失败: %v", err)
+		// 保存到已学习内容表（防重复）；存疑内容用户看不到，不算学过
+		return markLearned(tx, change, &record)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	fmt.Printf("✅ 学习记录已保存，ID: %d，版本: %d\n", record.ID, record.Version)
 	return &record, nil
 }
 
-func GetLearningHistory(learningType string, limit int) ([]models.LearningRecord, error) {
+func GetLearningHistory(ctx context.Context, learningType string, limit int) ([]models.LearningRecord, error) {
 	var records []models.LearningRecord
 	
-	query := DB.Model(&models.LearningRecord{})
+	query := DB.WithContext(ctx).Model(&models.LearningRecord{}).Scopes(PublicRecords)
 	if learningType != "" {
 		query = query.Where("type = ?", learningType)
 	}
@@ -179,7 +226,7 @@ func GetLearningHistory(learningType string, limit int) ([]models.LearningRecord
 	return records, nil
 }
 
-func GetGlobalStats() (map[string]models.TypeStats, error) {
+func GetGlobalStats(ctx context.Context) (map[string]models.TypeStats, error) {
 	type StatResult struct {
 		Type       string `json:"type"`
 		TotalDays  int64  `json:"total_days"`
@@ -188,7 +235,7 @@ func GetGlobalStats() (map[string]models.TypeStats, error) {
 
 	var results []StatResult
 	
-	err := DB.Model(&models.LearningRecord{}).
+	err := DB.WithContext(ctx).Model(&models.LearningRecord{}).Scopes(PublicRecords).
 		Select("type, COUNT(*) as total_days, COUNT(DISTINCT DATE(date)) as unique_days").
 		Group("type").
 		Find(&results).Error
@@ -231,14 +278,33 @@ func DebugShowAllRecords() {
     fmt.Println()
 }
 
-func DebugClearTodayRecords(learningType string) {
-    now := time.Now()
-    todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-    todayEnd := todayStart.Add(24 * time.Hour)
-    
-    result := DB.Where("type = ? AND date >= ? AND date < ?", learningType, todayStart, todayEnd).
-        Delete(&models.LearningRecord{})
-    
+func DebugClearTodayRecords(ctx context.Context, learningType string) {
+    todayStart, todayEnd := dayRange(time.Now())
+    change := Change{Actor: models.ActorDebug, Reason: "调试接口清理今日记录"}
+
+    var deleted int
+    err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        var records []models.LearningRecord
+        if err := tx.Where("type = ? AND date >= ? AND date < ?", learningType, todayStart, todayEnd).
+            Find(&records).Error; err != nil {
+            return err
+        }
+        for _, record := range records {
+            if err := tx.Delete(&models.LearningRecord{}, record.ID).Error; err != nil {
+                return err
+            }
+            if err := recordAudit(tx, change, models.AuditDelete, models.EntityLearningRecord, record.ID, record, nil); err != nil {
+                return err
+            }
+        }
+        deleted = len(records)
+        return nil
+    })
+    if err != nil {
+        fmt.Printf("❌ 清理今日 %s 记录失败: %v\n", models.GetLearningTypeName(learningType), err)
+        return
+    }
+
     fmt.Printf("🗑️  已清理今日 %s 记录，删除了 %d 条\n", 
-        models.GetLearningTypeName(learningType), result.RowsAffected)
-}
\ No newline at end of file
+        models.GetLearningTypeName(learningType), deleted)
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveFeedback 保存反馈，同一反馈人对同一条记录再次反馈时覆盖之前的结果
+func SaveFeedback(ctx context.Context, feedback *models.Feedback) error {
+	err := DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "record_id"}, {Name: "voter"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "voter <> ''"}}},
+		DoUpdates:   clause.AssignmentColumns([]string{"helpful", "created_at"}),
+	}).Create(feedback).Error
+	if err != nil {
+		return fmt.Errorf("保存反馈失败: %v", err)
+	}
+	return nil
+}
+
+// uniqueFeedbackVoters 建立每人对每条记录只有一条反馈的部分唯一索引，早期没有反馈人的反馈不受限制
+func uniqueFeedbackVoters(db *gorm.DB) error {
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_feedbacks_record_voter ON feedbacks (record_id, voter) WHERE voter <> ''").Error; err != nil {
+		return fmt.Errorf("创建反馈索引失败: %v", err)
+	}
+	return nil
+}
+
+// GetPromotedVariant 返回指定类型被提升的实验版本，没有时返回空字符串
+func GetPromotedVariant(ctx context.Context, learningType string) (string, error) {
+	var promotion models.PromptPromotion
+	err := DB.WithContext(ctx).Where("type = ?", learningType).First(&promotion).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("获取提升版本失败: %v", err)
+	}
+	return promotion.Variant, nil
+}
+
+func PromoteVariant(ctx context.Context, learningType string, variant string) error {
+	promotion := models.PromptPromotion{
+		Type:       learningType,
+		Variant:    variant,
+		PromotedAt: time.Now(),
+	}
+	err := DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&promotion).Error
+	if err != nil {
+		return fmt.Errorf("提升实验版本失败: %v", err)
+	}
+	return nil
+}
+
+func ClearPromotedVariant(ctx context.Context, learningType string) error {
+	err := DB.WithContext(ctx).Where("type = ?", learningType).Delete(&models.PromptPromotion{}).Error
+	if err != nil {
+		return fmt.Errorf("取消提升版本失败: %v", err)
+	}
+	return nil
+}
+
+// GetVariantStats 按提示词版本汇总指定类型的生成调用、产出记录和用户反馈
+func GetVariantStats(ctx context.Context, learningType string) (map[string]*models.VariantStats, error) {
+	stats := make(map[string]*models.VariantStats)
+	get := func(variant string) *models.VariantStats {
+		if stats[variant] == nil {
+			stats[variant] = &models.VariantStats{Variant: variant}
+		}
+		return stats[variant]
+	}
+
+	var attempts []struct {
+		Variant       string
+		Calls         int64
+		RequestErrors int64
+		ParseErrors   int64
+		Duplicates    int64
+	}
+	err := DB.WithContext(ctx).Model(&models.GenerationAttempt{}).
+		Select("variant, COUNT(*) AS calls, "+
+			"SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) AS request_errors, "+
+			"SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) AS parse_errors, "+
+			"SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) AS duplicates",
+			models.OutcomeRequestError, models.OutcomeParseError, models.OutcomeDuplicate).
+		Where("type = ? AND purpose = ?", learningType, models.PurposeGenerate).
+		Group("variant").
+		Scan(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计生成调用失败: %v", err)
+	}
+	for _, row := range attempts {
+		s := get(row.Variant)
+		s.Calls = row.Calls
+		s.RequestErrors = row.RequestErrors
+		s.ParseErrors = row.ParseErrors
+		s.Duplicates = row.Duplicates
+	}
+
+	var records []struct {
+		Variant string
+		Records int64
+	}
+	err = DB.WithContext(ctx).Model(&models.LearningRecord{}).
+		Select("variant, COUNT(*) AS records").
+		Where("type = ?", learningType).
+		Group("variant").
+		Scan(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计学习记录失败: %v", err)
+	}
+	for _, row := range records {
+		get(row.Variant).Records = row.Records
+	}
+
+	var feedback []struct {
+		Variant   string
+		Helpful   int64
+		Unhelpful int64
+	}
+	err = DB.WithContext(ctx).Table("feedbacks").
+		Select("learning_records.variant AS variant, "+
+			"SUM(CASE WHEN feedbacks.helpful THEN 1 ELSE 0 END) AS helpful, "+
+			"SUM(CASE WHEN feedbacks.helpful THEN 0 ELSE 1 END) AS unhelpful").
+		Joins("JOIN learning_records ON learning_records.id = feedbacks.record_id").
+		Where("feedbacks.type = ?", learningType).
+		Group("learning_records.variant").
+		Scan(&feedback).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计用户反馈失败: %v", err)
+	}
+	for _, row := range feedback {
+		s := get(row.Variant)
+		s.Helpful = row.Helpful
+		s.Unhelpful = row.Unhelpful
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+)
+
+// ListGlossaryRecords 返回带关键词的公开记录，只取词汇表需要的列，按日期倒序
+func ListGlossaryRecords(ctx context.Context, learningType string) ([]models.LearningRecord, error) {
+	query := DB.WithContext(ctx).Model(&models.LearningRecord{}).Scopes(PublicRecords).
+		Select("id, type, date, content, key_words").
+		Where("key_words <> ''")
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+
+	var records []models.LearningRecord
+	if err := query.Order("date DESC, id DESC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("获取词汇表记录失败: %v", err)
+	}
+	return records, nil
+}
+
+// GlossaryVersion 返回词汇表数据的版本：带关键词的公开记录的数量、ID 之和和最近的更新时间。
+// 发布、修改、删除记录或记录到了展示日期都会改变版本，只需一次聚合查询
+func GlossaryVersion(ctx context.Context, learningType string) (string, error) {
+	query := DB.WithContext(ctx).Model(&models.LearningRecord{}).Scopes(PublicRecords).
+		Select("COUNT(*) AS count, COALESCE(SUM(id), 0) AS id_sum, COALESCE(MAX(updated_at), '') AS updated_at").
+		Where("key_words <> ''")
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+
+	var row struct {
+		Count     int64
+		IDSum     int64
+		UpdatedAt string
+	}
+	if err := query.Scan(&row).Error; err != nil {
+		return "", fmt.Errorf("获取词汇表版本失败: %v", err)
+	}
+	return fmt.Sprintf("%d/%d/%s", row.Count, row.IDSum, row.UpdatedAt), nil
+}
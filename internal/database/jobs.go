@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SubmitGenerationJob 返回该类型未完成的任务，没有时创建新任务，created 表示是否新建。
+// 查找和创建在同一事务中进行，并由部分唯一索引保证每种类型只有一个未完成的任务：
+// 并发提交时创建失败的一方改为返回对方创建的任务
+func SubmitGenerationJob(ctx context.Context, learningType string) (*models.GenerationJob, bool, error) {
+	var job *models.GenerationJob
+	created := false
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		active, err := activeGenerationJob(tx, learningType)
+		if err != nil || active != nil {
+			job = active
+			return err
+		}
+
+		id, err := newJobID()
+		if err != nil {
+			return fmt.Errorf("生成任务ID失败: %v", err)
+		}
+		job = &models.GenerationJob{
+			ID:     id,
+			Type:   learningType,
+			Status: models.JobStatusPending,
+		}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err == nil {
+		return job, created, nil
+	}
+	if !isUniqueViolation(err) {
+		return nil, false, fmt.Errorf("创建生成任务失败: %v", err)
+	}
+
+	active, err := activeGenerationJob(DB.WithContext(ctx), learningType)
+	if err != nil {
+		return nil, false, err
+	}
+	if active == nil {
+		return nil, false, fmt.Errorf("创建生成任务失败: 任务冲突")
+	}
+	return active, false, nil
+}
+
+func GetGenerationJob(ctx context.Context, id string) (*models.GenerationJob, error) {
+	var job models.GenerationJob
+	err := DB.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取生成任务失败: %v", err)
+	}
+	return &job, nil
+}
+
+// activeGenerationJob 返回指定类型尚未结束的任务，用于合并重复提交
+func activeGenerationJob(db *gorm.DB, learningType string) (*models.GenerationJob, error) {
+	var job models.GenerationJob
+	err := db.Where("type = ? AND status IN ?", learningType, []string{models.JobStatusPending, models.JobStatusRunning}).
+		Order("created_at DESC").
+		First(&job).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取生成任务失败: %v", err)
+	}
+	return &job, nil
+}
+
+// uniqueActiveJobs 建立每种类型只能有一个未完成任务的部分唯一索引，
+// 建立前把多余的未完成任务（只保留最新的一个）标记为失败
+func uniqueActiveJobs(db *gorm.DB) error {
+	active := fmt.Sprintf("status IN ('%s', '%s')", models.JobStatusPending, models.JobStatusRunning)
+	err := db.Exec(`UPDATE generation_jobs SET status = ?, error = ?, finished_at = ? WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY type ORDER BY created_at DESC, id DESC) AS n
+				FROM generation_jobs WHERE `+active+`
+			) WHERE n > 1)`,
+		models.JobStatusFailed, "重复的未完成任务", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("清理重复的生成任务失败: %v", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_generation_jobs_active ON generation_jobs (type) WHERE " + active).Error; err != nil {
+		return fmt.Errorf("创建生成任务索引失败: %v", err)
+	}
+	return nil
+}
+
+func GetUnfinishedGenerationJobs(ctx context.Context) ([]models.GenerationJob, error) {
+	var jobs []models.GenerationJob
+	err := DB.WithContext(ctx).
+		Where("status IN ?", []string{models.JobStatusPending, models.JobStatusRunning}).
+		Order("created_at").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成任务失败: %v", err)
+	}
+	return jobs, nil
+}
+
+func MarkGenerationJobRunning(ctx context.Context, id string) error {
+	err := DB.WithContext(ctx).Model(&models.GenerationJob{}).
+		Where("id = ?", id).
+		Update("status", models.JobStatusRunning).Error
+	if err != nil {
+		return fmt.Errorf("更新任务状态失败: %v", err)
+	}
+	return nil
+}
+
+func FinishGenerationJob(ctx context.Context, id string, recordID *uint, jobErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.JobStatusSucceeded,
+		"record_id":   recordID,
+		"error":       "",
+		"finished_at": &now,
+	}
+	if jobErr != nil {
+		updates["status"] = models.JobStatusFailed
+		updates["error"] = jobErr.Error()
+	}
+
+	err := DB.WithContext(ctx).Model(&models.GenerationJob{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("更新任务状态失败: %v", err)
+	}
+	return nil
+}
+
+func GetLearningRecordByID(ctx context.Context, id uint) (*models.LearningRecord, error) {
+	var record models.LearningRecord
+	err := DB.WithContext(ctx).First(&record, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习记录失败: %v", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetOverride 返回某类型某一天的预设，没有时返回 nil
+func GetOverride(ctx context.Context, learningType string, day time.Time) (*models.Override, error) {
+	var override models.Override
+	err := DB.WithContext(ctx).Where("type = ? AND date = ?", learningType, day.Format("2006-01-02")).
+		First(&override).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取预设失败: %v", err)
+	}
+	return &override, nil
+}
+
+// GetOverrideByID 按ID获取预设，没有时返回 nil
+func GetOverrideByID(ctx context.Context, id uint) (*models.Override, error) {
+	var override models.Override
+	if err := DB.WithContext(ctx).First(&override, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取预设失败: %v", err)
+	}
+	return &override, nil
+}
+
+// ListOverrides 按日期列出预设，learningType、from、to 为空时不筛选，日期格式为 2006-01-02
+func ListOverrides(ctx context.Context, learningType, from, to string) ([]models.Override, error) {
+	var overrides []models.Override
+
+	query := DB.WithContext(ctx).Model(&models.Override{})
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+	if from != "" {
+		query = query.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+	}
+
+	if err := query.Order("date, type").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("获取预设列表失败: %v", err)
+	}
+	return overrides, nil
+}
+
+func CreateOverride(ctx context.Context, change Change, override *models.Override) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(override).Error; err != nil {
+			return fmt.Errorf("创建预设失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditCreate, models.EntityOverride, override.ID, nil, override)
+	})
+}
+
+func UpdateOverride(ctx context.Context, change Change, before models.Override, override *models.Override) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(override).Error; err != nil {
+			return fmt.Errorf("更新预设失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditUpdate, models.EntityOverride, override.ID, before, override)
+	})
+}
+
+func DeleteOverride(ctx context.Context, change Change, override *models.Override) error {
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Override{}, override.ID).Error; err != nil {
+			return fmt.Errorf("删除预设失败: %v", err)
+		}
+		return recordAudit(tx, change, models.AuditDelete, models.EntityOverride, override.ID, override, nil)
+	})
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// quizPoolSize 是出题时用来挑选干扰项的其他记录数量上限
+const quizPoolSize = 200
+
+// GetPublishedRecord 返回指定类型某一天对外展示的记录，没有时返回 nil
+func GetPublishedRecord(ctx context.Context, learningType string, day time.Time) (*models.LearningRecord, error) {
+	dayStart, dayEnd := dayRange(day)
+	var record models.LearningRecord
+	err := DB.WithContext(ctx).Scopes(PublicRecords).
+		Where("type = ? AND date >= ? AND date < ?", learningType, dayStart, dayEnd).
+		Order("pinned DESC, version DESC, id DESC").
+		First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// ListQuizPool 返回同类型的其他公开记录，按ID排序，用来挑选干扰项
+func ListQuizPool(ctx context.Context, learningType string, excludeID uint) ([]models.LearningRecord, error) {
+	var records []models.LearningRecord
+	err := DB.WithContext(ctx).Scopes(PublicRecords).
+		Where("type = ? AND id <> ?", learningType, excludeID).
+		Order("id").
+		Limit(quizPoolSize).
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取出题参考记录失败: %v", err)
+	}
+	return records, nil
+}
+
+// GetQuizByRecord 返回学习记录最新一份题目，没有时返回 nil
+func GetQuizByRecord(ctx context.Context, recordID uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if err := DB.WithContext(ctx).Where("record_id = ?", recordID).Order("id DESC").First(&quiz).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取练习题失败: %v", err)
+	}
+	return &quiz, nil
+}
+
+func GetQuiz(ctx context.Context, id uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if err := DB.WithContext(ctx).First(&quiz, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取练习题失败: %v", err)
+	}
+	return &quiz, nil
+}
+
+// SaveQuiz 为学习记录的当前版本保存一份新题目（新的ID），旧版本的题目保留不变。
+// 并发请求已为同一版本出过题时改用已保存的那份，quiz 被替换为数据库中的记录
+func SaveQuiz(ctx context.Context, quiz *models.Quiz) error {
+	result := DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(quiz)
+	if result.Error != nil {
+		return fmt.Errorf("保存练习题失败: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	existing, err := GetQuizByRecord(ctx, quiz.RecordID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("保存练习题失败: 未找到已保存的题目")
+	}
+	*quiz = *existing
+	return nil
+}
+
+// SaveModelQuestions 缓存模型为学习记录出的题目
+func SaveModelQuestions(ctx context.Context, quiz *models.Quiz) error {
+	err := DB.WithContext(ctx).Model(quiz).
+		Select("model_questions", "model_attempt_id").
+		Updates(quiz).Error
+	if err != nil {
+		return fmt.Errorf("保存模型出的练习题失败: %v", err)
+	}
+	return nil
+}
+
+func SaveQuizResult(ctx context.Context, result *models.QuizResult) error {
+	if err := DB.WithContext(ctx).Create(result).Error; err != nil {
+		return fmt.Errorf("保存练习成绩失败: %v", err)
+	}
+	return nil
+}
+
+// ListQuizResults 按提交时间倒序返回用户的练习成绩，可按类型筛选
+func ListQuizResults(ctx context.Context, userID uint, learningType string, limit int, offset int) ([]models.QuizResult, int64, error) {
+	query := DB.WithContext(ctx).Model(&models.QuizResult{}).Where("user_id = ?", userID)
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取练习成绩失败: %v", err)
+	}
+	var results []models.QuizResult
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&results).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取练习成绩失败: %v", err)
+	}
+	return results, total, nil
+}
+
+// QuizStats 是用户某类内容的练习次数和答题数
+type QuizStats struct {
+	Quizzes int
+	Correct int
+	Total   int
+}
+
+// GetQuizStats 按类型汇总用户的练习成绩
+func GetQuizStats(ctx context.Context, userID uint) (map[string]QuizStats, error) {
+	var rows []struct {
+		Type    string
+		Quizzes int64
+		Correct int64
+		Total   int64
+	}
+	err := DB.WithContext(ctx).Model(&models.QuizResult{}).
+		Where("user_id = ?", userID).
+		Select("type, COUNT(*) AS quizzes, SUM(correct) AS correct, SUM(total) AS total").
+		Group("type").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取练习统计失败: %v", err)
+	}
+
+	stats := make(map[string]QuizStats, len(rows))
+	for _, row := range rows {
+		stats[row.Type] = QuizStats{Quizzes: int(row.Quizzes), Correct: int(row.Correct), Total: int(row.Total)}
+	}
+	return stats, nil
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"everyday-study-backend/internal/review"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reviewableRecords 排除已删除和出处存疑的学习记录，到期复习和新内容使用同样的过滤条件
+func reviewableRecords(db *gorm.DB) *gorm.DB {
+	return db.Where("learning_records.deleted_at IS NULL").
+		Where("learning_records.verification IS NULL OR learning_records.verification <> ?", models.VerificationDisputed)
+}
+
+// dueReviews 是用户已到期的复习进度
+func dueReviews(ctx context.Context, userID uint, learningType string, now time.Time) *gorm.DB {
+	query := DB.WithContext(ctx).Model(&models.ReviewState{}).
+		Joins("JOIN learning_records ON learning_records.id = review_states.record_id").
+		Scopes(reviewableRecords).
+		Where("review_states.user_id = ? AND review_states.due_at <= ?", userID, now)
+	if learningType != "" {
+		query = query.Where("review_states.type = ?", learningType)
+	}
+	return query
+}
+
+// newReviews 是用户看过但从未复习过的往日内容，今天的内容从明天起进入复习
+func newReviews(ctx context.Context, userID uint, learningType string, now time.Time) *gorm.DB {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	query := DB.WithContext(ctx).Model(&models.UserRecord{}).
+		Joins("JOIN learning_records ON learning_records.id = user_records.record_id").
+		Joins("LEFT JOIN review_states ON review_states.user_id = user_records.user_id AND review_states.record_id = user_records.record_id").
+		Scopes(reviewableRecords).
+		Where("user_records.user_id = ? AND review_states.id IS NULL AND learning_records.date < ?", userID, today)
+	if learningType != "" {
+		query = query.Where("user_records.type = ?", learningType)
+	}
+	return query
+}
+
+// ListDueReviews 返回用户今天需要复习的内容，先列出到期的复习，再列出从未复习过的内容（ID 为 0，尚未保存）
+func ListDueReviews(ctx context.Context, userID uint, learningType string, now time.Time, limit int) ([]models.ReviewState, map[uint]models.LearningRecord, error) {
+	var states []models.ReviewState
+	err := dueReviews(ctx, userID, learningType, now).
+		Select("review_states.*").
+		Order("review_states.due_at, review_states.id").
+		Limit(limit).
+		Find(&states).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取复习队列失败: %v", err)
+	}
+
+	if remaining := limit - len(states); remaining > 0 {
+		var fresh []models.UserRecord
+		err := newReviews(ctx, userID, learningType, now).
+			Select("user_records.*").
+			Order("learning_records.date, learning_records.id").
+			Limit(remaining).
+			Find(&fresh).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取复习队列失败: %v", err)
+		}
+		for _, ur := range fresh {
+			states = append(states, models.ReviewState{
+				UserID:   userID,
+				RecordID: ur.RecordID,
+				Type:     ur.Type,
+				Ease:     review.DefaultEase,
+			})
+		}
+	}
+
+	ids := make([]uint, len(states))
+	for i, s := range states {
+		ids[i] = s.RecordID
+	}
+	var records []models.LearningRecord
+	if len(ids) > 0 {
+		if err := DB.WithContext(ctx).Find(&records, ids).Error; err != nil {
+			return nil, nil, fmt.Errorf("获取学习记录失败: %v", err)
+		}
+	}
+	byID := make(map[uint]models.LearningRecord, len(records))
+	for _, r := range records {
+		byID[r.ID] = r
+	}
+	return states, byID, nil
+}
+
+// CountDueReviews 按类型统计用户今天需要复习的内容数，包括从未复习过的内容
+func CountDueReviews(ctx context.Context, userID uint, now time.Time) (map[string]int, error) {
+	type CountResult struct {
+		Type  string
+		Count int64
+	}
+
+	var due, fresh []CountResult
+	err := dueReviews(ctx, userID, "", now).
+		Select("review_states.type AS type, COUNT(*) AS count").
+		Group("review_states.type").
+		Find(&due).Error
+	if err == nil {
+		err = newReviews(ctx, userID, "", now).
+			Select("user_records.type AS type, COUNT(*) AS count").
+			Group("user_records.type").
+			Find(&fresh).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("统计复习数量失败: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, c := range append(due, fresh...) {
+		counts[c.Type] += int(c.Count)
+	}
+	return counts, nil
+}
+
+// SubmitReview 记录一次复习的回忆评分，按 SM-2 算法更新复习进度
+func SubmitReview(ctx context.Context, userID uint, record *models.LearningRecord, grade int, now time.Time) (*models.ReviewState, error) {
+	var state models.ReviewState
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(models.ReviewState{UserID: userID, RecordID: record.ID}).
+			Attrs(models.ReviewState{Type: record.Type, Ease: review.DefaultEase}).
+			FirstOrInit(&state).Error
+		if err != nil {
+			return err
+		}
+
+		next, dueAt := review.Next(review.State{
+			Repetitions: state.Repetitions,
+			Interval:    state.Interval,
+			Ease:        state.Ease,
+			Lapses:      state.Lapses,
+		}, grade, now)
+		state.Repetitions = next.Repetitions
+		state.Interval = next.Interval
+		state.Ease = next.Ease
+		state.Lapses = next.Lapses
+		state.Reviews++
+		state.LastGrade = grade
+		state.DueAt = dueAt
+		state.LastReviewedAt = &now
+
+		return tx.Save(&state).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存复习进度失败: %v", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statsRecords 是日历统计的内容范围，列为 type、content、key_words 和 day（本地日期字符串）。
+// userID 为 0 时是全部公开内容，按内容日期统计；否则是该用户看过的内容，按首次查看的日期统计
+func statsRecords(ctx context.Context, userID uint, learningType string) *gorm.DB {
+	var query *gorm.DB
+	if userID == 0 {
+		query = DB.WithContext(ctx).Model(&models.LearningRecord{}).Scopes(PublicRecords).
+			Select("type, content, key_words, substr(date, 1, 10) AS day")
+	} else {
+		query = DB.WithContext(ctx).Model(&models.UserRecord{}).
+			Joins("JOIN learning_records ON learning_records.id = user_records.record_id AND learning_records.deleted_at IS NULL").
+			Where("user_records.user_id = ?", userID).
+			Select("learning_records.type AS type, learning_records.content AS content, learning_records.key_words AS key_words, " +
+				"substr(user_records.first_seen_at, 1, 10) AS day")
+	}
+	if learningType != "" {
+		query = query.Where("learning_records.type = ?", learningType)
+	}
+	return query
+}
+
+// GetCalendarStats 统计一年的学习日历、连续天数、每月数量以及关键词、作者和出处分布，
+// 汇总都在数据库中完成，每项最多返回 top 条
+func GetCalendarStats(ctx context.Context, userID uint, learningType string, year int, top int) (*models.CalendarStats, error) {
+	from := fmt.Sprintf("%04d-01-01", year)
+	to := fmt.Sprintf("%04d-01-01", year+1)
+	stats := &models.CalendarStats{}
+
+	var days []struct {
+		Day   string
+		Type  string
+		Count int
+	}
+	err := DB.WithContext(ctx).
+		Raw("SELECT day, type, COUNT(*) AS count FROM (?) AS r WHERE day >= ? AND day < ? GROUP BY day, type ORDER BY day, type",
+			statsRecords(ctx, userID, learningType), from, to).
+		Scan(&days).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计学习日历失败: %v", err)
+	}
+
+	byDay := make(map[string]*models.StatsDay, len(days))
+	for _, d := range days {
+		day, ok := byDay[d.Day]
+		if !ok {
+			day = &models.StatsDay{Date: d.Day, Types: []string{}}
+			byDay[d.Day] = day
+		}
+		day.Types = append(day.Types, d.Type)
+		day.Count += d.Count
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	for m := 0; m < 12; m++ {
+		stats.Monthly = append(stats.Monthly, models.MonthStats{Month: start.AddDate(0, m, 0).Format("2006-01")})
+	}
+	for day := start; day.Year() == year; day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		entry := models.StatsDay{Date: key, Types: []string{}}
+		if d, ok := byDay[key]; ok {
+			entry = *d
+			month := &stats.Monthly[day.Month()-1]
+			month.Count += d.Count
+			month.Days++
+			stats.ActiveDays++
+		}
+		stats.Days = append(stats.Days, entry)
+	}
+
+	if stats.CurrentStreak, stats.LongestStreak, err = streaks(ctx, userID, learningType); err != nil {
+		return nil, err
+	}
+	if stats.Keywords, err = keywordDistribution(ctx, userID, learningType, from, to, top); err != nil {
+		return nil, err
+	}
+	if stats.Authors, stats.Sources, err = attributionDistribution(ctx, userID, learningType, from, to, top); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// streaks 把有内容的日期按连续区间分组（日期减去序号相同即为同一段），返回当前和最长的连续天数
+func streaks(ctx context.Context, userID uint, learningType string) (int, int, error) {
+	var runs []struct {
+		LastDay string
+		Length  int
+	}
+	err := DB.WithContext(ctx).
+		Raw(`WITH days AS (SELECT DISTINCT day FROM (?) AS r),
+			runs AS (SELECT day, julianday(day) - ROW_NUMBER() OVER (ORDER BY day) AS grp FROM days)
+			SELECT MAX(day) AS last_day, COUNT(*) AS length FROM runs GROUP BY grp ORDER BY last_day DESC`,
+			statsRecords(ctx, userID, learningType)).
+		Scan(&runs).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("统计连续天数失败: %v", err)
+	}
+
+	today, _ := dayRange(time.Now())
+	current, longest := 0, 0
+	for i, run := range runs {
+		if i == 0 && (run.LastDay == today.Format("2006-01-02") || run.LastDay == today.AddDate(0, 0, -1).Format("2006-01-02")) {
+			current = run.Length
+		}
+		if run.Length > longest {
+			longest = run.Length
+		}
+	}
+	return current, longest, nil
+}
+
+// keywordDistribution 用递归查询按逗号拆分关键词，取冒号前的词语计数
+func keywordDistribution(ctx context.Context, userID uint, learningType string, from string, to string, top int) ([]models.DistributionItem, error) {
+	items := []models.DistributionItem{}
+	err := DB.WithContext(ctx).
+		Raw(`WITH RECURSIVE split(word, rest) AS (
+				SELECT '', key_words || ',' FROM (?) AS r WHERE day >= ? AND day < ? AND key_words <> ''
+				UNION ALL
+				SELECT trim(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1) FROM split WHERE rest <> ''
+			),
+			words AS (
+				SELECT CASE
+					WHEN instr(word, ':') > 0 THEN trim(substr(word, 1, instr(word, ':') - 1))
+					WHEN instr(word, '：') > 0 THEN trim(substr(word, 1, instr(word, '：') - 1))
+					ELSE word END AS name
+				FROM split WHERE word <> ''
+			)
+			SELECT name, COUNT(*) AS count FROM words WHERE name <> '' GROUP BY name ORDER BY count DESC, name LIMIT ?`,
+			statsRecords(ctx, userID, learningType), from, to, top).
+		Scan(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计关键词分布失败: %v", err)
+	}
+	return items, nil
+}
+
+// attributionDistribution 从“—— 唐 李白 《静夜思》”格式的出处中统计古诗词的作者和中医条文的典籍
+func attributionDistribution(ctx context.Context, userID uint, learningType string, from string, to string, top int) ([]models.DistributionItem, []models.DistributionItem, error) {
+	const parts = `WITH attrs AS (
+			SELECT type, trim(substr(content, instr(content, '——') + 2)) AS rest
+			FROM (?) AS r WHERE day >= ? AND day < ? AND instr(content, '——') > 0
+		),
+		parts AS (
+			SELECT type,
+				CASE WHEN instr(rest, '《') > 0 THEN trim(substr(rest, 1, instr(rest, '《') - 1)) ELSE rest END AS byline,
+				CASE WHEN instr(rest, '《') > 0 AND instr(rest, '》') > instr(rest, '《')
+					THEN substr(rest, instr(rest, '《') + 1, instr(rest, '》') - instr(rest, '《') - 1) ELSE '' END AS work
+			FROM attrs
+		)`
+
+	authors := []models.DistributionItem{}
+	err := DB.WithContext(ctx).
+		Raw(parts+`,
+			names AS (
+				SELECT CASE WHEN instr(byline, ' ') > 0 THEN trim(substr(byline, instr(byline, ' ') + 1)) ELSE byline END AS name
+				FROM parts WHERE type = 'chinese'
+			)
+			SELECT name, COUNT(*) AS count FROM names WHERE name <> '' GROUP BY name ORDER BY count DESC, name LIMIT ?`,
+			statsRecords(ctx, userID, learningType), from, to, top).
+		Scan(&authors).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("统计作者分布失败: %v", err)
+	}
+
+	sources := []models.DistributionItem{}
+	err = DB.WithContext(ctx).
+		Raw(parts+`,
+			books AS (
+				SELECT trim(CASE WHEN instr(work, '·') > 0 THEN substr(work, 1, instr(work, '·') - 1) ELSE work END) AS name
+				FROM parts WHERE type = 'tcm'
+			)
+			SELECT name, COUNT(*) AS count FROM books WHERE name <> '' GROUP BY name ORDER BY count DESC, name LIMIT ?`,
+			statsRecords(ctx, userID, learningType), from, to, top).
+		Scan(&sources).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("统计出处分布失败: %v", err)
+	}
+	return authors, sources, nil
+}
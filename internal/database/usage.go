@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func SaveGenerationAttempt(ctx context.Context, attempt *models.GenerationAttempt) error {
+	if err := DB.WithContext(ctx).Create(attempt).Error; err != nil {
+		return fmt.Errorf("保存生成记录失败: %v", err)
+	}
+	return nil
+}
+
+func GetGenerationAttempt(ctx context.Context, id uint) (*models.GenerationAttempt, error) {
+	var attempt models.GenerationAttempt
+	if err := DB.WithContext(ctx).First(&attempt, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取生成记录失败: %v", err)
+	}
+	return &attempt, nil
+}
+
+// ListGenerationAttempts 按时间倒序返回模型调用记录，type、purpose 为空时不筛选
+func ListGenerationAttempts(ctx context.Context, learningType string, purpose string, limit int) ([]models.GenerationAttempt, error) {
+	query := DB.WithContext(ctx).Order("created_at DESC").Limit(limit)
+	if learningType != "" {
+		query = query.Where("type = ?", learningType)
+	}
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+
+	var attempts []models.GenerationAttempt
+	if err := query.Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("获取生成记录失败: %v", err)
+	}
+	return attempts, nil
+}
+
+// GetTokenUsageSince 统计 since 之后所有模型调用消耗的 token 总数
+func GetTokenUsageSince(ctx context.Context, since time.Time) (int64, error) {
+	var total int64
+	err := DB.WithContext(ctx).Model(&models.GenerationAttempt{}).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("统计token用量失败: %v", err)
+	}
+	return total, nil
+}
+
+// GetUsageReport 按天和按月汇总各类型的 token 用量
+func GetUsageReport(ctx context.Context, days int, months int) ([]models.UsageRow, []models.UsageRow, error) {
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, now.Location())
+
+	// created_at 以本地时间字符串保存，直接截取日期部分，避免 DATE() 换算成 UTC
+	daily, err := usageGroupedBy(ctx, "substr(created_at, 1, 10)", dayStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	monthly, err := usageGroupedBy(ctx, "substr(created_at, 1, 7)", monthStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	return daily, monthly, nil
+}
+
+func usageGroupedBy(ctx context.Context, periodExpr string, since time.Time) ([]models.UsageRow, error) {
+	var rows []models.UsageRow
+	err := DB.WithContext(ctx).Model(&models.GenerationAttempt{}).
+		Select(periodExpr+" AS period, type, COUNT(*) AS calls, "+
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, "+
+			"SUM(total_tokens) AS total_tokens").
+		Where("created_at >= ?", since).
+		Group("period, type").
+		Order("period DESC, type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("汇总token用量失败: %v", err)
+	}
+	return rows, nil
+}
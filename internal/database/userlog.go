@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetVisibleRecord 返回对外可见的学习记录（出处无争议且不是未来日期），不可见或不存在时返回 nil。
+// 当天被替换的旧版本用户也可能看过，仍视为可见
+func GetVisibleRecord(ctx context.Context, id uint) (*models.LearningRecord, error) {
+	_, tomorrow := dayRange(time.Now())
+	var record models.LearningRecord
+	err := DB.WithContext(ctx).
+		Where("verification IS NULL OR verification <> ?", models.VerificationDisputed).
+		Where("date < ?", tomorrow).
+		First(&record, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// GetUserRecord 返回用户对某条记录的学习状态，没有时返回 nil
+func GetUserRecord(ctx context.Context, userID, recordID uint) (*models.UserRecord, error) {
+	var userRecord models.UserRecord
+	err := DB.WithContext(ctx).Where("user_id = ? AND record_id = ?", userID, recordID).First(&userRecord).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取学习状态失败: %v", err)
+	}
+	return &userRecord, nil
+}
+
+// UpdateUserRecord 读取（没有时新建）用户对某条记录的学习状态，用 apply 修改后保存
+func UpdateUserRecord(ctx context.Context, userID uint, record *models.LearningRecord, apply func(*models.UserRecord)) (*models.UserRecord, error) {
+	var userRecord models.UserRecord
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Where(models.UserRecord{UserID: userID, RecordID: record.ID}).
+			Attrs(models.UserRecord{Type: record.Type, FirstSeenAt: now, LastSeenAt: now}).
+			FirstOrInit(&userRecord).Error
+		if err != nil {
+			return err
+		}
+		apply(&userRecord)
+		return tx.Save(&userRecord).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存学习状态失败: %v", err)
+	}
+	return &userRecord, nil
+}
+
+// ListUserRecords 返回用户的收藏或笔记及对应的学习记录，按标记时间倒序
+func ListUserRecords(ctx context.Context, userID uint, q models.UserRecordQuery) ([]models.UserRecord, map[uint]models.LearningRecord, int64, error) {
+	var userRecords []models.UserRecord
+	var total int64
+
+	query := DB.WithContext(ctx).Model(&models.UserRecord{}).
+		Joins("JOIN learning_records ON learning_records.id = user_records.record_id AND learning_records.deleted_at IS NULL").
+		Where("user_records.user_id = ?", userID)
+	order := "user_records.last_seen_at DESC"
+	if q.Favorite {
+		query = query.Where("user_records.favorite = ?", true)
+		order = "user_records.favorited_at DESC"
+	}
+	if q.HasNote {
+		query = query.Where("user_records.note <> ''")
+		order = "user_records.note_updated_at DESC"
+	}
+	if q.Type != "" {
+		query = query.Where("user_records.type = ?", q.Type)
+	}
+	if keyword := strings.TrimSpace(q.Query); keyword != "" {
+		pattern := "%" + escapeLike(keyword) + "%"
+		query = query.Where(`(learning_records.content LIKE ? ESCAPE '\' OR learning_records.interpretation LIKE ? ESCAPE '\'
+			OR learning_records.key_words LIKE ? ESCAPE '\' OR user_records.note LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern, pattern)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, 0, fmt.Errorf("统计学习状态失败: %v", err)
+	}
+	err := query.Select("user_records.*").Order(order).Order("user_records.id DESC").
+		Limit(q.Limit).Offset(q.Offset).Find(&userRecords).Error
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("获取学习状态失败: %v", err)
+	}
+
+	ids := make([]uint, len(userRecords))
+	for i, ur := range userRecords {
+		ids[i] = ur.RecordID
+	}
+	var records []models.LearningRecord
+	if len(ids) > 0 {
+		if err := DB.WithContext(ctx).Find(&records, ids).Error; err != nil {
+			return nil, nil, 0, fmt.Errorf("获取学习记录失败: %v", err)
+		}
+	}
+	byID := make(map[uint]models.LearningRecord, len(records))
+	for _, r := range records {
+		byID[r.ID] = r
+	}
+	return userRecords, byID, total, nil
+}
+
+// GetUserStats 按类型统计用户看过、学会、收藏和写了笔记的记录数
+func GetUserStats(ctx context.Context, userID uint) (map[string]models.UserTypeStats, error) {
+	type StatResult struct {
+		Type      string
+		Viewed    int64
+		Studied   int64
+		Favorites int64
+		Notes     int64
+	}
+
+	var results []StatResult
+	err := DB.WithContext(ctx).Model(&models.UserRecord{}).
+		Joins("JOIN learning_records ON learning_records.id = user_records.record_id AND learning_records.deleted_at IS NULL").
+		Where("user_records.user_id = ?", userID).
+		Select(`user_records.type AS type, COUNT(*) AS viewed,
+			SUM(CASE WHEN user_records.studied THEN 1 ELSE 0 END) AS studied,
+			SUM(CASE WHEN user_records.favorite THEN 1 ELSE 0 END) AS favorites,
+			SUM(CASE WHEN user_records.note <> '' THEN 1 ELSE 0 END) AS notes`).
+		Group("user_records.type").
+		Find(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取用户统计失败: %v", err)
+	}
+
+	stats := make(map[string]models.UserTypeStats)
+	for _, result := range results {
+		stats[result.Type] = models.UserTypeStats{
+			TypeName:  models.GetLearningTypeName(result.Type),
+			Viewed:    int(result.Viewed),
+			Studied:   int(result.Studied),
+			Favorites: int(result.Favorites),
+			Notes:     int(result.Notes),
+		}
+	}
+	return stats, nil
+}
+
+// escapeLike 转义 LIKE 中的通配符，配合 ESCAPE '\' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
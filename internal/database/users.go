@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUsernameTaken       = errors.New("用户名已被注册")
+	ErrInvalidRefreshToken = errors.New("refresh token 无效或已过期")
+)
+
+// CreateUser 创建用户，用户名已存在时返回 ErrUsernameTaken。
+// 由用户名唯一索引判断重复，并发注册同一用户名时只有一个会成功
+func CreateUser(ctx context.Context, user *models.User) error {
+	if err := DB.WithContext(ctx).Create(user).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrUsernameTaken
+		}
+		return fmt.Errorf("创建用户失败: %v", err)
+	}
+	return nil
+}
+
+// GetUserByUsername 按用户名获取用户，不存在时返回 nil
+func GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := DB.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+	return &user, nil
+}
+
+// GetUserByID 按ID获取用户，不存在时返回 nil
+func GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := DB.WithContext(ctx).First(&user, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+	return &user, nil
+}
+
+func TouchLogin(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	user.LastLoginAt = &now
+	if err := DB.WithContext(ctx).Model(user).Update("last_login_at", now).Error; err != nil {
+		return fmt.Errorf("更新登录时间失败: %v", err)
+	}
+	return nil
+}
+
+func SaveRefreshToken(ctx context.Context, userID uint, tokenID string, expiresAt time.Time) error {
+	token := models.RefreshToken{UserID: userID, TokenID: tokenID, ExpiresAt: expiresAt}
+	if err := DB.WithContext(ctx).Create(&token).Error; err != nil {
+		return fmt.Errorf("保存 refresh token 失败: %v", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken 吊销旧的 refresh token 并保存新令牌。旧令牌已被吊销说明它可能被盗用，
+// 此时吊销该用户的全部令牌，要求重新登录
+func RotateRefreshToken(ctx context.Context, userID uint, oldID, newID string, expiresAt time.Time) error {
+	reused := false
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old models.RefreshToken
+		if err := tx.Where("token_id = ? AND user_id = ?", oldID, userID).First(&old).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("获取 refresh token 失败: %v", err)
+		}
+		if old.RevokedAt != nil {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+		if time.Now().After(old.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if err := tx.Model(&old).Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("吊销 refresh token 失败: %v", err)
+		}
+		token := models.RefreshToken{UserID: userID, TokenID: newID, ExpiresAt: expiresAt}
+		if err := tx.Create(&token).Error; err != nil {
+			return fmt.Errorf("保存 refresh token 失败: %v", err)
+		}
+		return nil
+	})
+
+	if reused {
+		if revokeErr := RevokeUserTokens(ctx, userID); revokeErr != nil {
+			return revokeErr
+		}
+	}
+	return err
+}
+
+// RevokeRefreshToken 吊销一个 refresh token，用于退出登录
+func RevokeRefreshToken(ctx context.Context, userID uint, tokenID string) error {
+	err := DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("token_id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("吊销 refresh token 失败: %v", err)
+	}
+	return nil
+}
+
+func RevokeUserTokens(ctx context.Context, userID uint) error {
+	err := DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("吊销 refresh token 失败: %v", err)
+	}
+	return nil
+}
+
+// RecordView 记录用户看过某条学习记录，重复查看只更新最后查看时间
+func RecordView(ctx context.Context, userID uint, record *models.LearningRecord) error {
+	now := time.Now()
+	var view models.UserRecord
+	err := DB.WithContext(ctx).
+		Where(models.UserRecord{UserID: userID, RecordID: record.ID}).
+		Attrs(models.UserRecord{Type: record.Type, FirstSeenAt: now}).
+		Assign(models.UserRecord{LastSeenAt: now}).
+		FirstOrCreate(&view).Error
+	if err != nil {
+		return fmt.Errorf("记录学习历史失败: %v", err)
+	}
+	return nil
+}
+
+// GetUserHistory 按日期倒序返回用户看过的学习记录，learningType 为空时返回全部类型
+func GetUserHistory(ctx context.Context, userID uint, learningType string, limit int) ([]models.LearningRecord, error) {
+	var records []models.LearningRecord
+
+	query := DB.WithContext(ctx).
+		Joins("JOIN user_records ON user_records.record_id = learning_records.id").
+		Where("user_records.user_id = ?", userID)
+	if learningType != "" {
+		query = query.Where("learning_records.type = ?", learningType)
+	}
+
+	err := query.Order("learning_records.date DESC, user_records.last_seen_at DESC").
+		Limit(limit).
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取学习历史失败: %v", err)
+	}
+	return records, nil
+}
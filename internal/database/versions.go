@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 同一类型同一天可以有多个版本的学习记录：每次重新生成都新建一个版本，
+// 旧版本标记为 superseded 但保留，对外只展示未被替换的版本。
+
+func dayRange(day time.Time) (time.Time, time.Time) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return dayStart, dayStart.Add(24 * time.Hour)
+}
+
+// publishRecord 让 record 成为同类型当天对外展示的版本：当天其他展示中的版本标记为旧版本，
+// record 没有版本号时分配下一个版本号。record 不是固定记录时，当天固定的记录不受影响；
+// 是固定记录时，原先固定的记录一并取消固定。调用方负责随后保存 record。
+func publishRecord(tx *gorm.DB, change Change, record *models.LearningRecord) error {
+	dayStart, dayEnd := dayRange(record.Date)
+
+	query := tx.Where("type = ? AND date >= ? AND date < ? AND id <> ? AND superseded = ?", record.Type, dayStart, dayEnd, record.ID, false).
+		Where("verification IS NULL OR verification <> ?", models.VerificationDisputed)
+	if !record.Pinned {
+		query = query.Where("pinned = ?", false)
+	}
+
+	var current []models.LearningRecord
+	if err := query.Find(&current).Error; err != nil {
+		return fmt.Errorf("查询当天记录失败: %v", err)
+	}
+	for _, r := range current {
+		before := r
+		r.Superseded = true
+		r.Pinned = false
+		if err := tx.Save(&r).Error; err != nil {
+			return fmt.Errorf("替换旧版本失败: %v", err)
+		}
+		if err := recordAudit(tx, change, models.AuditUpdate, models.EntityLearningRecord, r.ID, before, r); err != nil {
+			return err
+		}
+	}
+	if len(current) > 0 {
+		fmt.Printf("🗂️  %d 条当天记录已转为旧版本\n", len(current))
+	}
+
+	if record.Version == 0 {
+		version, err := nextVersion(tx, record.Type, dayStart, dayEnd)
+		if err != nil {
+			return err
+		}
+		record.Version = version
+	}
+	record.Superseded = false
+	return nil
+}
+
+// nextVersion 返回同类型当天的下一个版本号，已删除的版本也计入，版本号不会重复
+func nextVersion(tx *gorm.DB, learningType string, dayStart, dayEnd time.Time) (int, error) {
+	var maxVersion int
+	err := tx.Unscoped().Model(&models.LearningRecord{}).
+		Where("type = ? AND date >= ? AND date < ?", learningType, dayStart, dayEnd).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&maxVersion).Error
+	if err != nil {
+		return 0, fmt.Errorf("获取版本号失败: %v", err)
+	}
+	return maxVersion + 1, nil
+}
+
+// backfillVersions 为引入版本号之前的记录按日期顺序编号，只在版本号列首次加入时执行一次
+func backfillVersions(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 新加入的列在已有记录上为 NULL
+		if err := tx.Exec("UPDATE learning_records SET version = 0 WHERE version IS NULL").Error; err != nil {
+			return fmt.Errorf("初始化记录版本失败: %v", err)
+		}
+
+		var records []models.LearningRecord
+		err := tx.Unscoped().
+			Where("version = 0 AND (verification IS NULL OR verification <> ?)", models.VerificationDisputed).
+			Order("date, id").
+			Find(&records).Error
+		if err != nil {
+			return fmt.Errorf("初始化记录版本失败: %v", err)
+		}
+		for _, r := range records {
+			dayStart, dayEnd := dayRange(r.Date)
+			version, err := nextVersion(tx, r.Type, dayStart, dayEnd)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&r).UpdateColumn("version", version).Error; err != nil {
+				return fmt.Errorf("初始化记录版本失败: %v", err)
+			}
+		}
+		if len(records) > 0 {
+			fmt.Printf("🗂️  已为 %d 条早期记录补充版本号\n", len(records))
+		}
+		return nil
+	})
+}
+
+// GetRecordVersions 返回某一天的全部版本（不含出处存疑和已删除的记录），按版本号升序
+func GetRecordVersions(ctx context.Context, learningType string, day time.Time) ([]models.LearningRecord, error) {
+	dayStart, dayEnd := dayRange(day)
+
+	var records []models.LearningRecord
+	err := DB.WithContext(ctx).
+		Where("type = ? AND date >= ? AND date < ?", learningType, dayStart, dayEnd).
+		Where("verification IS NULL OR verification <> ?", models.VerificationDisputed).
+		Order("version, id").
+		Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取历史版本失败: %v", err)
+	}
+	return records, nil
+}
+
+// SupersedeTodayRecords 把今日展示中的记录转为旧版本（固定记录除外），下次访问时生成新版本
+func SupersedeTodayRecords(ctx context.Context, change Change, learningType string) (int, error) {
+	todayStart, todayEnd := dayRange(time.Now())
+
+	var count int
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var records []models.LearningRecord
+		if err := tx.Where("type = ? AND date >= ? AND date < ? AND superseded = ? AND pinned = ?", learningType, todayStart, todayEnd, false, false).
+			Find(&records).Error; err != nil {
+			return fmt.Errorf("查询今日记录失败: %v", err)
+		}
+		for _, r := range records {
+			before := r
+			r.Superseded = true
+			if err := tx.Save(&r).Error; err != nil {
+				return fmt.Errorf("替换旧版本失败: %v", err)
+			}
+			if err := recordAudit(tx, change, models.AuditUpdate, models.EntityLearningRecord, r.ID, before, r); err != nil {
+				return err
+			}
+		}
+		count = len(records)
+		return nil
+	})
+	return count, err
+}
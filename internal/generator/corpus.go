@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"context"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/corpus"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// generateFromCorpus 按语料顺序挑选第一条尚未学过的内容。
+// 语料缺少释义或关键词时，仅在有可用模型（explainTier 非空）时请模型补全；补全失败就跳过该条。
+// alwaysExplain 为 true 时（语料模式）即使语料自带释义也优先使用模型对原文的讲解。
+func (g *Generator) generateFromCorpus(ctx context.Context, learningType string, learned []string, explainTier *config.ModelTier, alwaysExplain bool) (*ParsedContent, error) {
+	llmAvailable := explainTier != nil
+	candidates := corpus.Unlearned(learningType, learned)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("本地语料已全部学完")
+	}
+
+	for _, item := range candidates {
+		parsed := &ParsedContent{
+			Content:        item.Content(),
+			Interpretation: item.Interpretation,
+			KeyWords:       item.KeyWords,
+			SourceID:       item.ID,
+		}
+		if item.Complete() && !(alwaysExplain && llmAvailable) {
+			log.Printf("📖 使用本地语料: %s", item.ID)
+			return parsed, nil
+		}
+		if !llmAvailable {
+			continue
+		}
+
+		explanation, err := g.explain(ctx, learningType, *explainTier, parsed.Content)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("⚠️  模型讲解语料 %s 失败，后续只使用完整语料: %v", item.ID, err)
+			llmAvailable = false
+			if item.Complete() {
+				log.Printf("📖 使用本地语料: %s", item.ID)
+				return parsed, nil
+			}
+			continue
+		}
+
+		if alwaysExplain || parsed.Interpretation == "" {
+			parsed.Interpretation = explanation.Interpretation
+		}
+		if len(explanation.KeyWords) > 0 && (alwaysExplain || len(parsed.KeyWords) == 0) {
+			parsed.KeyWords = explanation.KeyWords
+		}
+		parsed.AttemptID = explanation.AttemptID
+		if parsed.Interpretation != "" && len(parsed.KeyWords) > 0 {
+			log.Printf("📖 使用本地语料（模型讲解）: %s", item.ID)
+			return parsed, nil
+		}
+	}
+
+	return nil, fmt.Errorf("没有可直接使用的本地语料")
+}
+
+// explain 请模型为给定原文撰写释义和关键词
+func (g *Generator) explain(ctx context.Context, learningType string, tier config.ModelTier, text string) (*ParsedContent, error) {
+	if err := g.checkBudget(ctx); err != nil {
+		return nil, err
+	}
+
+	settings := g.volcanoClient.Settings(learningType, models.PurposeExplain, tier)
+	started := time.Now()
+	aiResponse, err := g.volcanoClient.CallExplainAPI(ctx, settings, learningType, text)
+	if err != nil {
+		g.recordAttempt(ctx, learningType, settings, false, started, aiResponse, err, nil)
+		return nil, fmt.Errorf("调用AI API失败: %v", err)
+	}
+
+	explanation, err := parseExplanation(aiResponse.Choices[0].Message.Content, learningType)
+	attemptID := g.recordAttempt(ctx, learningType, settings, false, started, aiResponse, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation.AttemptID = attemptID
+	return explanation, nil
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"context"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/curriculum"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// curriculumUnit 返回该类型启用的学习路径和 day 这天要学的单元序号；
+// 没有启用路径或路径已全部学完时返回 nil
+func (g *Generator) curriculumUnit(ctx context.Context, learningType string, day time.Time) (*curriculum.Path, int) {
+	progress, err := database.GetActiveCurriculum(ctx, learningType)
+	if err != nil {
+		log.Printf("⚠️  查询学习路径失败: %v", err)
+		return nil, 0
+	}
+	if progress == nil {
+		return nil, 0
+	}
+
+	path, ok := curriculum.Get(progress.Path)
+	if !ok {
+		log.Printf("⚠️  启用的学习路径 %s 不存在", progress.Path)
+		return nil, 0
+	}
+	seq := progress.NextUnit(day.Format("2006-01-02"), len(path.Units))
+	if seq == 0 {
+		log.Printf("🎓 学习路径「%s」已全部学完，改为自由生成", path.Title)
+		return nil, 0
+	}
+	return path, seq
+}
+
+// generateFromUnit 请模型讲解学习路径单元给出的原文，依次尝试回退链中的模型
+func (g *Generator) generateFromUnit(ctx context.Context, learningType string, unit *curriculum.Unit, profile config.GenerationProfile) (*ParsedContent, error) {
+	err := fmt.Errorf("回退链中没有可用的模型")
+	for _, tier := range profile.Chain {
+		if tier.IsCorpus() {
+			continue
+		}
+
+		var explanation *ParsedContent
+		explanation, err = g.explain(ctx, learningType, tier, unit.Content())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("⚠️  %s 讲解学习路径原文失败: %v", tier.Model, err)
+			continue
+		}
+		if len(explanation.KeyWords) == 0 {
+			err = fmt.Errorf("讲解缺少关键词")
+			continue
+		}
+
+		return &ParsedContent{
+			Content:          unit.Content(),
+			Interpretation:   explanation.Interpretation,
+			KeyWords:         explanation.KeyWords,
+			Verification:     models.VerificationVerified,
+			VerificationNote: "学习路径原文",
+			AttemptID:        explanation.AttemptID,
+			Tier:             tier.Model,
+		}, nil
+	}
+	return nil, err
+}
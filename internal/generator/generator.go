@@ -0,0 +1,519 @@
+package generator
+
+import (
+	"context"
+	"everyday-study-backend/internal/api"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/corpus"
+	"everyday-study-backend/internal/curriculum"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Generator 负责生成并保存每日学习内容，供接口和定时任务共用
+type Generator struct {
+	volcanoClient *api.VolcanoClient
+	config        *config.Config
+	baseCtx       context.Context
+
+	mu       sync.Mutex
+	inflight map[string]*generation
+}
+
+// New 创建生成器。ctx 代表服务生命周期，服务关闭时取消它即可中断进行中的生成
+func New(ctx context.Context, cfg *config.Config) *Generator {
+	return &Generator{
+		volcanoClient: api.NewVolcanoClient(cfg),
+		config:        cfg,
+		baseCtx:       ctx,
+		inflight:      make(map[string]*generation),
+	}
+}
+
+func (g *Generator) Client() *api.VolcanoClient {
+	return g.volcanoClient
+}
+
+func (g *Generator) Profile(learningType string) config.GenerationProfile {
+	return g.config.Profile(learningType)
+}
+
+// PrimarySettings 返回指定类型回退链第一个模型的生成参数
+func (g *Generator) PrimarySettings(learningType string) api.CallSettings {
+	return g.volcanoClient.Settings(learningType, models.PurposeGenerate, g.config.Profile(learningType).PrimaryTier())
+}
+
+// 生成进度事件类型
+const (
+	EventStatus = "status"
+	EventDelta  = "delta"
+	// 订阅者积压时改发的完整已生成文本，客户端用它替换已显示的文本
+	EventSnapshot = "snapshot"
+)
+
+// 生成阶段
+const (
+	StatusGenerating = "generating"
+	StatusParsing    = "parsing"
+	StatusSaving     = "saving"
+	StatusCorpus     = "corpus"
+	StatusVerifying  = "verifying"
+	// 上一级失败，改用回退链的下一级；此前推送的文本片段作废
+	StatusFallback = "fallback"
+)
+
+type Event struct {
+	Kind string
+	Data string
+}
+
+type generation struct {
+	done   chan struct{}
+	record *models.LearningRecord
+	err    error
+	stream bool
+	// 本次生成的主题和来自的预设，模型生成时使用
+	theme      string
+	overrideID *uint
+	// 本次生成对应的学习路径、单元序号和写入提示词的单元描述
+	curriculum string
+	unit       int
+	target     string
+
+	mu          sync.Mutex
+	status      string
+	partial     strings.Builder
+	subscribers map[chan Event]struct{}
+}
+
+// publish 向所有订阅者广播事件；订阅者来不及消费时丢弃它积压的事件，改发当前阶段和完整的已生成文本
+func (call *generation) publish(ev Event) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+
+	switch ev.Kind {
+	case EventStatus:
+		call.status = ev.Data
+		if ev.Data == StatusFallback {
+			call.partial.Reset()
+		}
+	case EventDelta:
+		call.partial.WriteString(ev.Data)
+	}
+
+	for ch := range call.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			call.resync(ch)
+		}
+	}
+}
+
+// resync 清空订阅者积压的事件并补发当前进度，调用方需持有 call.mu
+func (call *generation) resync(ch chan Event) {
+	for drained := false; !drained; {
+		select {
+		case <-ch:
+		default:
+			drained = true
+		}
+	}
+	if call.status != "" {
+		ch <- Event{Kind: EventStatus, Data: call.status}
+	}
+	ch <- Event{Kind: EventSnapshot, Data: call.partial.String()}
+}
+
+// subscribe 注册订阅者，并先补发当前阶段和已生成的文本
+func (call *generation) subscribe() chan Event {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+
+	ch := make(chan Event, 256)
+	if call.status != "" {
+		ch <- Event{Kind: EventStatus, Data: call.status}
+	}
+	if call.partial.Len() > 0 {
+		ch <- Event{Kind: EventDelta, Data: call.partial.String()}
+	}
+	call.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (call *generation) unsubscribe(ch chan Event) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	delete(call.subscribers, ch)
+}
+
+// Subscription 是一次流式订阅，Events 在生成结束前持续推送进度
+type Subscription struct {
+	Events <-chan Event
+	Done   <-chan struct{}
+
+	call *generation
+	ch   chan Event
+}
+
+func (s *Subscription) Result() (*models.LearningRecord, error) {
+	return s.call.record, s.call.err
+}
+
+func (s *Subscription) Close() {
+	s.call.unsubscribe(s.ch)
+}
+
+// Generate 生成指定类型的今日内容并保存。
+// 调用方 ctx 取消只会让等待提前返回，已经开始的生成会继续完成并写入缓存；
+// 同一类型同时只会有一次生成在进行。
+func (g *Generator) Generate(ctx context.Context, learningType string) (*models.LearningRecord, error) {
+	call := g.start(learningType, false)
+
+	select {
+	case <-call.done:
+		return call.record, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe 以流式模式开始（或加入进行中的）生成，并订阅进度事件。
+// 调用方用完后需要 Close；提前 Close 不会中断生成。
+func (g *Generator) Subscribe(learningType string) *Subscription {
+	call := g.start(learningType, true)
+	ch := call.subscribe()
+	return &Subscription{
+		Events: ch,
+		Done:   call.done,
+		call:   call,
+		ch:     ch,
+	}
+}
+
+// Regenerate 强制重新生成指定类型的今日内容。
+// 与 Generate 不同，有生成在进行时不会复用它的结果，而是等它结束后再开始新的一次。
+func (g *Generator) Regenerate(ctx context.Context, learningType string) (*models.LearningRecord, error) {
+	for {
+		g.mu.Lock()
+		running, ok := g.inflight[learningType]
+		if !ok {
+			call := g.launch(learningType, false)
+			g.mu.Unlock()
+
+			select {
+			case <-call.done:
+				return call.record, call.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		g.mu.Unlock()
+
+		log.Printf("⏳ %s 内容正在生成中，结束后重新生成", models.GetLearningTypeName(learningType))
+		select {
+		case <-running.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (g *Generator) start(learningType string, stream bool) *generation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	call, ok := g.inflight[learningType]
+	if ok {
+		log.Printf("⏳ %s 内容正在生成中，等待结果", models.GetLearningTypeName(learningType))
+		return call
+	}
+
+	// 调用方查询今日内容之后、进入这里之前，上一次生成可能刚刚完成；
+	// 在锁内再查一次，已有今日内容时直接返回，避免发布多余的版本替换掉用户刚看到的内容
+	record, err := database.GetTodayLearningRecord(g.baseCtx, learningType)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	} else if record != nil {
+		call = &generation{
+			done:        make(chan struct{}),
+			record:      record,
+			subscribers: make(map[chan Event]struct{}),
+		}
+		close(call.done)
+		return call
+	}
+	return g.launch(learningType, stream)
+}
+
+// launch 开始一次新的生成，调用方需持有 g.mu
+func (g *Generator) launch(learningType string, stream bool) *generation {
+	call := &generation{
+		done:        make(chan struct{}),
+		stream:      stream,
+		subscribers: make(map[chan Event]struct{}),
+	}
+	g.inflight[learningType] = call
+	go g.run(learningType, call)
+	return call
+}
+
+func (g *Generator) run(learningType string, call *generation) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("生成过程发生异常: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.inflight, learningType)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.record, call.err = g.generate(g.baseCtx, learningType, call)
+	if call.err != nil {
+		log.Printf("❌ 生成 %s 内容失败: %v", models.GetLearningTypeName(learningType), call.err)
+	}
+}
+
+func (g *Generator) generate(ctx context.Context, learningType string, call *generation) (*models.LearningRecord, error) {
+	// 管理员固定了今日内容时不再生成
+	pinned, err := database.GetPinnedRecord(ctx, learningType, time.Now())
+	if err != nil {
+		log.Printf("⚠️  查询固定内容失败: %v", err)
+	} else if pinned != nil {
+		log.Printf("📌 %s 今日内容已固定（ID: %d），跳过生成", models.GetLearningTypeName(learningType), pinned.ID)
+		return pinned, nil
+	}
+
+	// 管理员预设了今日内容时直接发布，预设了主题时按主题生成
+	override, err := database.GetOverride(ctx, learningType, time.Now())
+	if err != nil {
+		log.Printf("⚠️  查询预设失败: %v", err)
+	}
+	if override != nil && override.HasContent() {
+		log.Printf("🗓️  使用 %s 今日预设内容（ID: %d）", models.GetLearningTypeName(learningType), override.ID)
+		return g.save(ctx, learningType, &ParsedContent{
+			Content:          override.Content,
+			Interpretation:   override.Interpretation,
+			KeyWords:         (&models.LearningRecord{KeyWords: override.KeyWords}).FormatKeyWords(),
+			Verification:     models.VerificationVerified,
+			VerificationNote: "管理员预设内容",
+			Tier:             config.OverrideTier,
+			Theme:            override.Theme,
+			OverrideID:       &override.ID,
+		}, call)
+	}
+	// 预设了主题时当天按主题生成；否则启用了学习路径的类型学习路径的下一个单元
+	if override == nil {
+		if path, seq := g.curriculumUnit(ctx, learningType, time.Now()); path != nil {
+			call.curriculum, call.unit, call.target = path.Name, seq, path.Target(seq)
+			log.Printf("🧭 学习路径「%s」第 %d 单元: %s", path.Title, seq, path.Unit(seq).Title)
+		}
+	}
+	if call.curriculum == "" {
+		call.theme, call.overrideID = g.theme(learningType, override, time.Now())
+		if call.theme != "" {
+			log.Printf("🎐 今日主题: %s", call.theme)
+		}
+	}
+
+	learnedContent, err := database.GetLearnedContent(ctx, learningType)
+	if err != nil {
+		return nil, fmt.Errorf("获取已学习内容失败: %v", err)
+	}
+
+	log.Printf("📚 已学习内容数量: %d", len(learnedContent))
+
+	profile := g.config.Profile(learningType)
+
+	// 单元给出了原文时只请模型讲解原文，讲解失败再请模型围绕该单元挑选内容
+	if path, ok := curriculum.Get(call.curriculum); ok && path.Unit(call.unit).Text != "" {
+		call.publish(Event{Kind: EventStatus, Data: StatusGenerating})
+		parsedContent, err := g.generateFromUnit(ctx, learningType, path.Unit(call.unit), profile)
+		if err == nil {
+			parsedContent.Curriculum, parsedContent.Unit = call.curriculum, call.unit
+			return g.save(ctx, learningType, parsedContent, call)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("⚠️  未能讲解学习路径原文，改由模型围绕该单元生成: %v", err)
+	}
+
+	// 按学习路径学习时不从语料中随意挑选
+	if g.config.IsCorpusMode(learningType) && call.curriculum == "" {
+		call.publish(Event{Kind: EventStatus, Data: StatusCorpus})
+		primary := profile.PrimaryTier()
+		parsedContent, err := g.generateFromCorpus(ctx, learningType, learnedContent, &primary, true)
+		if err == nil {
+			parsedContent.Tier = config.CorpusTier
+			return g.save(ctx, learningType, parsedContent, call)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("⚠️  语料模式未能选出内容，改由模型自由生成: %v", err)
+	}
+
+	// 同一次生成的各级模型使用同一个提示词实验版本
+	variant := g.pickVariant(ctx, learningType, profile)
+
+	// 依次尝试回退链的各级；explainTier 记录最近一个接口可用的模型，供本地语料补全释义
+	var explainTier *config.ModelTier
+	var failures []string
+	for i, tier := range profile.Chain {
+		if i > 0 {
+			log.Printf("↪️  改用回退链第 %d 级: %s", i+1, tier.Model)
+			call.publish(Event{Kind: EventStatus, Data: StatusFallback})
+		}
+
+		var parsedContent *ParsedContent
+		if tier.IsCorpus() {
+			call.publish(Event{Kind: EventStatus, Data: StatusCorpus})
+			parsedContent, err = g.generateFromCorpus(ctx, learningType, learnedContent, explainTier, false)
+			tier.Model = config.CorpusTier
+		} else {
+			var providerOK bool
+			parsedContent, providerOK, err = g.generateWithModel(ctx, learningType, learnedContent, tier, variant, call)
+			if providerOK {
+				explainTier = &profile.Chain[i]
+			}
+			if err == nil {
+				err = g.checkAttribution(ctx, learningType, parsedContent, tier, call)
+			}
+		}
+
+		if err == nil {
+			parsedContent.Tier = tier.Model
+			return g.save(ctx, learningType, parsedContent, call)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Printf("⚠️  回退链第 %d 级 %s 失败: %v", i+1, tier.Model, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", tier.Model, err))
+	}
+
+	return nil, fmt.Errorf("回退链全部失败: %s", strings.Join(failures, "；"))
+}
+
+// checkAttribution 核验模型生成内容的出处。出处存疑的内容仍然保存备查，
+// 但不对外展示，并返回错误让回退链继续尝试下一级。
+func (g *Generator) checkAttribution(ctx context.Context, learningType string, parsedContent *ParsedContent, tier config.ModelTier, call *generation) error {
+	call.publish(Event{Kind: EventStatus, Data: StatusVerifying})
+	parsedContent.Verification, parsedContent.VerificationNote = g.verify(ctx, learningType, parsedContent)
+	if parsedContent.Verification != models.VerificationDisputed {
+		return nil
+	}
+
+	log.Printf("🚫 出处存疑: %s", parsedContent.VerificationNote)
+	parsedContent.Tier = tier.Model
+	if _, err := g.save(ctx, learningType, parsedContent, call); err != nil {
+		log.Printf("❌ 保存存疑记录失败: %v", err)
+	}
+	return fmt.Errorf("出处存疑: %s", parsedContent.VerificationNote)
+}
+
+func (g *Generator) save(ctx context.Context, learningType string, parsedContent *ParsedContent, call *generation) (*models.LearningRecord, error) {
+	if parsedContent.Verification == "" {
+		parsedContent.Verification, parsedContent.VerificationNote = g.verify(ctx, learningType, parsedContent)
+	}
+
+	learningContent := models.LearningContent{
+		Type:             models.LearningType(learningType),
+		Content:          parsedContent.Content,
+		Interpretation:   parsedContent.Interpretation,
+		KeyWords:         parsedContent.KeyWords,
+		SourceID:         parsedContent.SourceID,
+		Verification:     parsedContent.Verification,
+		VerificationNote: parsedContent.VerificationNote,
+		AttemptID:        parsedContent.AttemptID,
+		Tier:             parsedContent.Tier,
+		Variant:          parsedContent.Variant,
+		Theme:            parsedContent.Theme,
+		OverrideID:       parsedContent.OverrideID,
+		Curriculum:       parsedContent.Curriculum,
+		Unit:             parsedContent.Unit,
+		Date:             time.Now(),
+	}
+
+	call.publish(Event{Kind: EventStatus, Data: StatusSaving})
+
+	change := database.Change{Actor: models.ActorSystem, Reason: "自动生成今日内容"}
+	switch {
+	case parsedContent.Verification == models.VerificationDisputed:
+		change.Reason = "出处存疑，留存备查"
+	case parsedContent.Tier == config.OverrideTier:
+		change.Reason = fmt.Sprintf("发布预设内容 #%d", *parsedContent.OverrideID)
+	case parsedContent.Curriculum != "":
+		change.Reason = fmt.Sprintf("学习路径 %s 第 %d 单元", parsedContent.Curriculum, parsedContent.Unit)
+	case parsedContent.Theme != "":
+		change.Reason = fmt.Sprintf("按主题「%s」生成", parsedContent.Theme)
+	}
+	record, err := database.SaveLearningRecord(ctx, change, learningType, learningContent)
+	if err != nil {
+		return nil, fmt.Errorf("保存学习记录失败: %v", err)
+	}
+
+	return record, nil
+}
+
+// generateWithModel 请模型挑选并解释新内容。providerOK 表示模型接口本身可用，
+// 失败只发生在解析阶段，此时兜底流程仍可请模型补全释义。
+func (g *Generator) generateWithModel(ctx context.Context, learningType string, learnedContent []string, tier config.ModelTier, variant *config.PromptVariant, call *generation) (*ParsedContent, bool, error) {
+	if err := g.checkBudget(ctx); err != nil {
+		return nil, false, err
+	}
+
+	call.publish(Event{Kind: EventStatus, Data: StatusGenerating})
+
+	settings := g.volcanoClient.Settings(learningType, models.PurposeGenerate, tier).WithTheme(call.theme).WithUnit(call.target)
+	if variant != nil {
+		settings = settings.WithVariant(*variant)
+	}
+	started := time.Now()
+	var aiResponse *models.VolcanoAPIResponse
+	var err error
+	if call.stream {
+		aiResponse, err = g.volcanoClient.CallVolcanoAPIStream(ctx, settings, learningType, learnedContent, func(delta string) {
+			call.publish(Event{Kind: EventDelta, Data: delta})
+		})
+	} else {
+		aiResponse, err = g.volcanoClient.CallVolcanoAPI(ctx, settings, learningType, learnedContent)
+	}
+	if err != nil {
+		g.recordAttempt(ctx, learningType, settings, call.stream, started, aiResponse, err, nil)
+		return nil, false, fmt.Errorf("调用AI API失败: %v", err)
+	}
+
+	content := aiResponse.Choices[0].Message.Content
+	preview := []rune(content)
+	log.Printf("🤖 AI原始响应: %s...", string(preview[:min(100, len(preview))]))
+
+	call.publish(Event{Kind: EventStatus, Data: StatusParsing})
+
+	parsedContent, err := ParseAIContent(content, learningType)
+	if err == nil && corpus.ContainsLearned(parsedContent.Content, learnedContent) {
+		err = errDuplicate
+	}
+	attemptID := g.recordAttempt(ctx, learningType, settings, call.stream, started, aiResponse, nil, err)
+	if err == errDuplicate {
+		return nil, true, err
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("解析AI内容失败: %v", err)
+	}
+
+	parsedContent.AttemptID = attemptID
+	parsedContent.Variant = settings.Variant
+	parsedContent.Theme = settings.Theme
+	parsedContent.OverrideID = call.overrideID
+	parsedContent.Curriculum, parsedContent.Unit = call.curriculum, call.unit
+	return parsedContent, true, nil
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"context"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Submit 创建异步生成任务并立即返回，任务状态持久化在数据库中。
+// 同一类型已有未完成的任务时直接复用。
+func (g *Generator) Submit(ctx context.Context, learningType string) (*models.GenerationJob, error) {
+	job, created, err := database.SubmitGenerationJob(ctx, learningType)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		log.Printf("♻️  复用进行中的生成任务: %s", job.ID)
+		return job, nil
+	}
+
+	log.Printf("📮 已创建 %s 生成任务: %s", models.GetLearningTypeName(learningType), job.ID)
+	go g.runJob(job.ID, learningType)
+
+	return job, nil
+}
+
+// ResumeJobs 在服务启动时恢复上次未完成的任务，过期任务直接标记失败
+func (g *Generator) ResumeJobs(ctx context.Context) {
+	jobs, err := database.GetUnfinishedGenerationJobs(ctx)
+	if err != nil {
+		log.Printf("❌ 恢复生成任务失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for _, job := range jobs {
+		if job.CreatedAt.Before(todayStart) {
+			if err := database.FinishGenerationJob(ctx, job.ID, nil, fmt.Errorf("任务已过期")); err != nil {
+				log.Printf("❌ 标记过期任务失败: %v", err)
+			}
+			continue
+		}
+
+		log.Printf("🔁 恢复 %s 生成任务: %s", models.GetLearningTypeName(job.Type), job.ID)
+		go g.runJob(job.ID, job.Type)
+	}
+}
+
+func (g *Generator) runJob(jobID string, learningType string) {
+	ctx := g.baseCtx
+
+	if err := database.MarkGenerationJobRunning(ctx, jobID); err != nil {
+		log.Printf("❌ %v", err)
+	}
+
+	// 提交任务后可能已有其他请求生成了今日内容，直接复用
+	record, err := database.GetTodayLearningRecord(ctx, learningType)
+	if err == nil && record == nil {
+		record, err = g.Generate(ctx, learningType)
+	}
+
+	// 服务关闭导致的中断保持 running 状态，下次启动时恢复
+	if ctx.Err() != nil {
+		return
+	}
+
+	var recordID *uint
+	if record != nil {
+		recordID = &record.ID
+	}
+	if err := database.FinishGenerationJob(ctx, jobID, recordID, err); err != nil {
+		log.Printf("❌ %v", err)
+	}
+}
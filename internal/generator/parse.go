@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"encoding/json"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type ParsedContent struct {
+	Content        string
+	Interpretation string
+	KeyWords       []string
+	SourceID       string
+
+	Verification     string
+	VerificationNote string
+	// 产出该内容的模型调用
+	AttemptID *uint
+	// 产出该内容的回退链层级：模型名或 corpus
+	Tier string
+	// 生成时使用的提示词实验版本
+	Variant string
+	// 生成主题和对应的预设
+	Theme      string
+	OverrideID *uint
+	// 按学习路径产出时对应的路径和单元
+	Curriculum string
+	Unit       int
+}
+
+func trimCodeFence(contentStr string) string {
+	contentStr = strings.TrimPrefix(contentStr, "```json")
+	contentStr = strings.TrimSuffix(contentStr, "```")
+	return strings.TrimSpace(contentStr)
+}
+
+// ParseAIContent 解析模型生成的学习内容，缺少该类型的原文字段或释义时返回错误
+func ParseAIContent(contentStr string, learningType string) (*ParsedContent, error) {
+	contentStr = trimCodeFence(contentStr)
+
+	var aiData models.AIContent
+	err := json.Unmarshal([]byte(contentStr), &aiData)
+	if err == nil {
+		result := extractContentFromAIData(&aiData, learningType)
+		if result != nil {
+			return result, nil
+		}
+	}
+
+	log.Printf("直接解析失败，尝试灵活解析: %v", err)
+
+	return flexibleParseContent(contentStr, learningType)
+}
+
+func extractContentFromAIData(aiData *models.AIContent, learningType string) *ParsedContent {
+	result := &ParsedContent{
+		Interpretation: aiData.Interpretation,
+		KeyWords:       []string{},
+	}
+
+	switch strings.ToLower(learningType) {
+	case "english":
+		if aiData.Proverb != "" {
+			result.Content = aiData.Proverb
+		}
+		if len(aiData.KeyWords) > 0 {
+			for _, kw := range aiData.KeyWords {
+				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
+			}
+		}
+	case "chinese":
+		if aiData.Poem != "" {
+			result.Content = aiData.Poem
+		}
+		if len(aiData.KeyWords) > 0 {
+			for _, kw := range aiData.KeyWords {
+				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
+			}
+		}
+	case "tcm":
+		if aiData.TCMText != "" {
+			result.Content = aiData.TCMText
+		}
+		if len(aiData.KeyConcepts) > 0 {
+			for _, kc := range aiData.KeyConcepts {
+				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kc.Concept, kc.Meaning))
+			}
+		}
+	}
+
+	if result.Content == "" || result.Interpretation == "" {
+		return nil
+	}
+
+	return result
+}
+
+func flexibleParseContent(contentStr string, learningType string) (*ParsedContent, error) {
+	var rawContent map[string]interface{}
+	err := json.Unmarshal([]byte(contentStr), &rawContent)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析JSON内容: %v", err)
+	}
+
+	result := &ParsedContent{
+		KeyWords: []string{},
+	}
+
+	switch strings.ToLower(learningType) {
+	case "english":
+		result.Content = getStringValue(rawContent, "proverb")
+		result.Interpretation = getStringValue(rawContent, "interpretation")
+		result.KeyWords = parseKeyItems(rawContent, "key_words", "word", "meaning")
+
+	case "chinese":
+		result.Content = getStringValue(rawContent, "poem")
+		result.Interpretation = getStringValue(rawContent, "interpretation")
+		result.KeyWords = parseKeyItems(rawContent, "key_words", "word", "meaning")
+
+	case "tcm":
+		result.Content = getStringValue(rawContent, "tcm_text")
+		result.Interpretation = getStringValue(rawContent, "interpretation")
+		result.KeyWords = parseKeyItems(rawContent, "key_concepts", "concept", "meaning")
+	}
+
+	if result.Content == "" {
+		return nil, fmt.Errorf("解析后的主要内容为空")
+	}
+
+	if result.Interpretation == "" {
+		return nil, fmt.Errorf("解析后的释义为空")
+	}
+
+	return result, nil
+}
+
+func getStringValue(data map[string]interface{}, key string) string {
+	if value, exists := data[key]; exists {
+		if str, ok := value.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+func parseKeyItems(data map[string]interface{}, arrayKey, itemKey, meaningKey string) []string {
+	var result []string
+
+	if value, exists := data[arrayKey]; exists {
+		if array, ok := value.([]interface{}); ok {
+			for _, item := range array {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					itemValue := getStringValue(itemMap, itemKey)
+					meaningValue := getStringValue(itemMap, meaningKey)
+					if itemValue != "" && meaningValue != "" {
+						result = append(result, fmt.Sprintf("%s: %s", itemValue, meaningValue))
+					}
+				} else if str, ok := item.(string); ok {
+					result = append(result, str)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// parseExplanation 解析只包含释义和关键词的模型响应，Content 由调用方填写
+func parseExplanation(contentStr string, learningType string) (*ParsedContent, error) {
+	var rawContent map[string]interface{}
+	if err := json.Unmarshal([]byte(trimCodeFence(contentStr)), &rawContent); err != nil {
+		return nil, fmt.Errorf("无法解析JSON内容: %v", err)
+	}
+
+	result := &ParsedContent{
+		Interpretation: getStringValue(rawContent, "interpretation"),
+	}
+	if strings.ToLower(learningType) == "tcm" {
+		result.KeyWords = parseKeyItems(rawContent, "key_concepts", "concept", "meaning")
+	} else {
+		result.KeyWords = parseKeyItems(rawContent, "key_words", "word", "meaning")
+	}
+
+	if result.Interpretation == "" {
+		return nil, fmt.Errorf("解析后的释义为空")
+	}
+
+	return result, nil
+}
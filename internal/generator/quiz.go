@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/models"
+	"everyday-study-backend/internal/quiz"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxModelQuestions = 3
+
+// QuizQuestions 请模型为一条学习记录另出几道选择题，返回校验通过的题目和对应的调用记录ID
+func (g *Generator) QuizQuestions(ctx context.Context, record *models.LearningRecord) ([]quiz.Question, *uint, error) {
+	if err := g.checkBudget(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	prompt := fmt.Sprintf("内容：%s\n释义：%s\n关键词：%s", record.Content, record.Interpretation, strings.Join(record.FormatKeyWords(), "；"))
+	settings := g.volcanoClient.Settings(record.Type, models.PurposeQuiz, g.config.Profile(record.Type).PrimaryTier())
+	started := time.Now()
+	aiResponse, err := g.volcanoClient.CallQuizAPI(ctx, settings, prompt)
+	if err != nil {
+		g.recordAttempt(ctx, record.Type, settings, false, started, aiResponse, err, nil)
+		return nil, nil, fmt.Errorf("模型出题失败: %v", err)
+	}
+
+	questions, err := parseQuizQuestions(aiResponse.Choices[0].Message.Content)
+	attemptID := g.recordAttempt(ctx, record.Type, settings, false, started, aiResponse, nil, err)
+	if err != nil {
+		return nil, attemptID, err
+	}
+	return questions, attemptID, nil
+}
+
+// parseQuizQuestions 解析模型出的题目，丢弃答案不在选项中的题
+func parseQuizQuestions(content string) ([]quiz.Question, error) {
+	var result struct {
+		Questions []quiz.Question `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(trimCodeFence(content)), &result); err != nil {
+		return nil, fmt.Errorf("解析模型出的题目失败: %v", err)
+	}
+
+	var questions []quiz.Question
+	for _, q := range result.Questions {
+		if len(questions) >= maxModelQuestions {
+			break
+		}
+		q.Prompt = strings.TrimSpace(q.Prompt)
+		if q.Prompt == "" || len(q.Options) < 2 || len(q.Options) > 6 || !containsOption(q.Options, q.Answer) {
+			continue
+		}
+		q.ID = fmt.Sprintf("m%d", len(questions)+1)
+		q.Kind = quiz.KindModel
+		questions = append(questions, q)
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("模型没有给出有效的题目")
+	}
+	return questions, nil
+}
+
+func containsOption(options []string, answer string) bool {
+	for _, o := range options {
+		if o == answer {
+			return true
+		}
+	}
+	return false
+}
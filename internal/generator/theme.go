@@ -0,0 +1,25 @@
+package generator
+
+import (
+	"everyday-study-backend/internal/calendar"
+	"everyday-study-backend/internal/models"
+	"strings"
+	"time"
+)
+
+// theme 返回当天的生成主题：优先使用管理员预设的主题；否则对开启自动主题的类型，
+// 使用当天的节气或传统节日。没有主题时返回空字符串
+func (g *Generator) theme(learningType string, override *models.Override, day time.Time) (string, *uint) {
+	if override != nil && strings.TrimSpace(override.Theme) != "" {
+		return strings.TrimSpace(override.Theme), &override.ID
+	}
+	if !g.config.IsAutoTheme(learningType) {
+		return "", nil
+	}
+
+	var names []string
+	for _, o := range calendar.OccasionsOn(day) {
+		names = append(names, o.Name)
+	}
+	return strings.Join(names, "、"), nil
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"everyday-study-backend/internal/api"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"log"
+	"time"
+)
+
+var (
+	errBudgetExceeded = errors.New("token 预算已用完，暂停调用模型")
+	errDuplicate      = errors.New("内容与已学内容重复")
+)
+
+// BudgetStatus 返回今日、本月的 token 用量与预算
+func (g *Generator) BudgetStatus(ctx context.Context) (models.BudgetStatus, error) {
+	status := models.BudgetStatus{
+		DailyLimit:   g.config.DailyTokenBudget,
+		MonthlyLimit: g.config.MonthlyTokenBudget,
+	}
+
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var err error
+	if status.TodayUsed, err = database.GetTokenUsageSince(ctx, todayStart); err != nil {
+		return status, err
+	}
+	if status.MonthUsed, err = database.GetTokenUsageSince(ctx, monthStart); err != nil {
+		return status, err
+	}
+
+	status.Exceeded = (status.DailyLimit > 0 && status.TodayUsed >= status.DailyLimit) ||
+		(status.MonthlyLimit > 0 && status.MonthUsed >= status.MonthlyLimit)
+	return status, nil
+}
+
+// checkBudget 在调用模型前检查预算，统计失败时不阻断生成
+func (g *Generator) checkBudget(ctx context.Context) error {
+	if g.config.DailyTokenBudget <= 0 && g.config.MonthlyTokenBudget <= 0 {
+		return nil
+	}
+
+	status, err := g.BudgetStatus(ctx)
+	if err != nil {
+		log.Printf("⚠️  检查token预算失败: %v", err)
+		return nil
+	}
+	if status.Exceeded {
+		return errBudgetExceeded
+	}
+	return nil
+}
+
+// recordAttempt 保存一次模型调用的参数、耗时、用量和解析结果，返回记录ID。
+// parseErr 为 errDuplicate 时记为内容重复。
+func (g *Generator) recordAttempt(ctx context.Context, learningType string, settings api.CallSettings, stream bool, started time.Time, resp *models.VolcanoAPIResponse, callErr error, parseErr error) *uint {
+	attempt := models.GenerationAttempt{
+		Type:          learningType,
+		Purpose:       settings.Purpose,
+		Model:         settings.Model,
+		PromptVersion: settings.PromptVersion,
+		Variant:       settings.Variant,
+		Temperature:   settings.Temperature,
+		MaxTokens:     settings.MaxTokens,
+		Stream:        stream,
+		LatencyMs:     time.Since(started).Milliseconds(),
+		Outcome:       models.OutcomeOK,
+	}
+
+	if resp != nil {
+		if resp.Model != "" {
+			attempt.Model = resp.Model
+		}
+		attempt.PromptTokens = resp.Usage.PromptTokens
+		attempt.CompletionTokens = resp.Usage.CompletionTokens
+		attempt.TotalTokens = resp.Usage.TotalTokens
+		attempt.RawResponse = resp.Raw
+		if len(resp.Choices) > 0 {
+			attempt.ReasoningContent = resp.Choices[0].Message.ReasoningContent
+		}
+	}
+
+	switch {
+	case callErr != nil:
+		attempt.Outcome = models.OutcomeRequestError
+		attempt.Error = callErr.Error()
+	case errors.Is(parseErr, errDuplicate):
+		attempt.Outcome = models.OutcomeDuplicate
+		attempt.Error = parseErr.Error()
+	case parseErr != nil:
+		attempt.Outcome = models.OutcomeParseError
+		attempt.Error = parseErr.Error()
+	}
+
+	if err := database.SaveGenerationAttempt(ctx, &attempt); err != nil {
+		log.Printf("⚠️  %v", err)
+		return nil
+	}
+	return &attempt.ID
+}
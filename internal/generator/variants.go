@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"context"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/database"
+	"log"
+	"math/rand"
+)
+
+// pickVariant 为一次生成挑选提示词实验版本：有提升的版本时固定使用它，
+// 否则按权重随机分配。未配置实验时返回 nil。
+func (g *Generator) pickVariant(ctx context.Context, learningType string, profile config.GenerationProfile) *config.PromptVariant {
+	if len(profile.Variants) == 0 {
+		return nil
+	}
+
+	promoted, err := database.GetPromotedVariant(ctx, learningType)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if v, ok := profile.Variant(promoted); promoted != "" && ok {
+		return &v
+	}
+
+	total := 0
+	for _, v := range profile.Variants {
+		total += v.Weight
+	}
+	n := rand.Intn(total)
+	for i := range profile.Variants {
+		n -= profile.Variants[i].Weight
+		if n < 0 {
+			log.Printf("🧪 %s 使用提示词版本: %s", learningType, profile.Variants[i].Name)
+			return &profile.Variants[i]
+		}
+	}
+	return nil
+}
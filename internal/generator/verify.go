@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/corpus"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+func sameDynasty(a, b string) bool {
+	trim := func(s string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(s, "代"), "朝")
+	}
+	return trim(a) == trim(b)
+}
+
+// verify 核验内容标注的出处：先查本地语料，查不到时再独立请模型复核。
+// 返回核验结果和说明。
+func (g *Generator) verify(ctx context.Context, learningType string, parsed *ParsedContent) (string, string) {
+	if parsed.SourceID != "" {
+		return models.VerificationVerified, fmt.Sprintf("内容来自本地语料 %s", parsed.SourceID)
+	}
+
+	learningType = strings.ToLower(learningType)
+	if learningType != "chinese" && learningType != "tcm" {
+		return models.VerificationUnverified, ""
+	}
+
+	attr, ok := corpus.ParseAttribution(parsed.Content)
+	if !ok {
+		return models.VerificationUnverified, "内容未标注出处"
+	}
+
+	if status, note, ok := verifyWithCorpus(learningType, attr); ok {
+		return status, note
+	}
+
+	return g.verifyWithModel(ctx, learningType, parsed.Content)
+}
+
+// verifyWithCorpus 用本地语料核验出处，ok 为 false 表示本地没有可参考的条目
+func verifyWithCorpus(learningType string, attr *corpus.Attribution) (string, string, bool) {
+	if item, found := corpus.FindByText(learningType, attr.Text); found {
+		var problems []string
+		switch learningType {
+		case "chinese":
+			if attr.Author != "" && attr.Author != item.Author {
+				problems = append(problems, fmt.Sprintf("作者应为%s", item.Author))
+			}
+			if attr.Dynasty != "" && !sameDynasty(attr.Dynasty, item.Dynasty) {
+				problems = append(problems, fmt.Sprintf("朝代应为%s", item.Dynasty))
+			}
+			if attr.Work != "" && !corpus.SameTitle(attr.Work, item.Title) {
+				problems = append(problems, fmt.Sprintf("出处应为《%s》", item.Title))
+			}
+		case "tcm":
+			book := strings.SplitN(item.Title, "·", 2)[0]
+			if attr.Work != "" && !strings.Contains(attr.Work, item.Source) && !strings.Contains(attr.Work, book) {
+				problems = append(problems, fmt.Sprintf("出处应为《%s·%s》", item.Source, item.Title))
+			}
+		}
+
+		if len(problems) > 0 {
+			return models.VerificationDisputed, fmt.Sprintf("与本地语料 %s 不符：%s", item.ID, strings.Join(problems, "，")), true
+		}
+		return models.VerificationVerified, fmt.Sprintf("与本地语料 %s 一致", item.ID), true
+	}
+
+	// 原文不在语料中，但作品在语料中且作者不同，可以确定是张冠李戴
+	if learningType == "chinese" && attr.Work != "" && attr.Author != "" {
+		items := corpus.FindByTitle(learningType, attr.Work)
+		if len(items) > 0 {
+			for _, item := range items {
+				if item.Author == attr.Author {
+					return "", "", false
+				}
+			}
+			return models.VerificationDisputed, fmt.Sprintf("《%s》的作者应为%s", items[0].Title, items[0].Author), true
+		}
+	}
+
+	return "", "", false
+}
+
+func (g *Generator) verifyWithModel(ctx context.Context, learningType string, content string) (string, string) {
+	if err := g.checkBudget(ctx); err != nil {
+		return models.VerificationUnverified, "本地无参考，" + err.Error()
+	}
+
+	settings := g.volcanoClient.Settings(learningType, models.PurposeVerify, g.config.Profile(learningType).PrimaryTier())
+	started := time.Now()
+	aiResponse, err := g.volcanoClient.CallVerifyAPI(ctx, settings, content)
+	if err != nil {
+		g.recordAttempt(ctx, learningType, settings, false, started, aiResponse, err, nil)
+		log.Printf("⚠️  模型复核出处失败: %v", err)
+		return models.VerificationUnverified, "本地无参考，模型复核失败"
+	}
+
+	var check models.AttributionCheck
+	err = json.Unmarshal([]byte(trimCodeFence(aiResponse.Choices[0].Message.Content)), &check)
+	g.recordAttempt(ctx, learningType, settings, false, started, aiResponse, nil, err)
+	if err != nil {
+		log.Printf("⚠️  解析模型复核结果失败: %v", err)
+		return models.VerificationUnverified, "本地无参考，模型复核结果无法解析"
+	}
+
+	if check.Correct {
+		return models.VerificationVerified, "模型复核：" + check.Reason
+	}
+
+	note := "模型复核：" + check.Reason
+	if check.CorrectAttribution != "" {
+		note += "；正确出处：" + check.CorrectAttribution
+	}
+	return models.VerificationDisputed, note
+}
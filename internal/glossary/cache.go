@@ -0,0 +1,39 @@
+package glossary
+
+import "sync"
+
+// Cache 按学习类型缓存合并好的词汇表。version 是调用方给出的数据版本，
+// 与缓存时不同说明记录有变化，需要重新合并
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	version  string
+	glossary *Glossary
+}
+
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]cacheEntry)}
+}
+
+// Get 返回 learningType（空字符串表示全部类型）在 version 下的词汇表，缓存失效时调用 build 重新合并。
+// 合并不持有锁，并发的请求可能各自合并一次，结果相同
+func (c *Cache) Get(learningType string, version string, build func() (*Glossary, error)) (*Glossary, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[learningType]
+	c.mu.Unlock()
+	if ok && entry.version == version {
+		return entry.glossary, nil
+	}
+
+	g, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[learningType] = cacheEntry{version: version, glossary: g}
+	c.mu.Unlock()
+	return g, nil
+}
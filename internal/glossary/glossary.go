@@ -0,0 +1,214 @@
+package glossary
+
+import (
+	"everyday-study-backend/internal/corpus"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 词语后面附带的注音或说明，例如“正气（zhèng qì）”，合并时忽略
+var notePattern = regexp.MustCompile(`[（(][^）)]*[）)]`)
+
+// maxContextRunes 是出处上下文的最大长度
+const maxContextRunes = 60
+
+// Source 是一条带关键词的学习记录
+type Source struct {
+	RecordID uint
+	Type     string
+	Date     string
+	Content  string
+	KeyWords []string
+}
+
+// Occurrence 是词语在一条记录中的出现：当时的写法、释义和所在的原文句子
+type Occurrence struct {
+	RecordID uint
+	Type     string
+	Date     string
+	Word     string
+	Meaning  string
+	Context  string
+}
+
+// Meaning 是合并后的一种释义，Count 为使用这种释义的记录数
+type Meaning struct {
+	Text  string
+	Count int
+}
+
+// Term 是合并了各条记录中同一词语的词条，Term 取最常见的写法
+type Term struct {
+	Key         string
+	Term        string
+	Types       []string
+	Meanings    []Meaning
+	Occurrences []Occurrence
+}
+
+// Glossary 是合并好的词汇表，可按键查找词条
+type Glossary struct {
+	// 按出现的记录数从多到少排序
+	Terms []Term
+	byKey map[string]int
+}
+
+// Lookup 按 Key 的结果查找词条
+func (g *Glossary) Lookup(key string) (*Term, bool) {
+	i, ok := g.byKey[key]
+	if !ok {
+		return nil, false
+	}
+	return &g.Terms[i], true
+}
+
+// Key 返回词语合并用的键：去掉注音说明，统一全角半角和大小写，去掉空白和标点
+func Key(word string) string {
+	return corpus.Fold(notePattern.ReplaceAllString(word, ""))
+}
+
+// Build 把各条记录的关键词按 Key 合并成词条，释义相同（同样按 Fold 比较）的合并计数。
+// 写法和释义次数相同时以 sources 中靠前的为准；词条按出现的记录数从多到少排序，记录数相同时按键排序
+func Build(sources []Source) *Glossary {
+	byKey := make(map[string]*Term)
+	spellings := make(map[string]*spellingCount)
+	var keys []string
+
+	for _, src := range sources {
+		for _, raw := range src.KeyWords {
+			kw, ok := corpus.ParseKeyword(raw)
+			if !ok {
+				continue
+			}
+			key := Key(kw.Word)
+			if key == "" {
+				continue
+			}
+			term, ok := byKey[key]
+			if !ok {
+				term = &Term{Key: key}
+				byKey[key] = term
+				spellings[key] = &spellingCount{counts: make(map[string]int)}
+				keys = append(keys, key)
+			}
+			spellings[key].add(kw.Word)
+			addType(term, src.Type)
+			addMeaning(term, kw.Meaning)
+			term.Occurrences = append(term.Occurrences, Occurrence{
+				RecordID: src.RecordID,
+				Type:     src.Type,
+				Date:     src.Date,
+				Word:     kw.Word,
+				Meaning:  kw.Meaning,
+				Context:  sentenceOf(src.Content, kw.Word),
+			})
+		}
+	}
+
+	terms := make([]Term, 0, len(keys))
+	for _, key := range keys {
+		term := byKey[key]
+		term.Term = spellings[key].mostCommon()
+		sort.SliceStable(term.Meanings, func(i, j int) bool {
+			return term.Meanings[i].Count > term.Meanings[j].Count
+		})
+		terms = append(terms, *term)
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		if len(terms[i].Occurrences) != len(terms[j].Occurrences) {
+			return len(terms[i].Occurrences) > len(terms[j].Occurrences)
+		}
+		return terms[i].Key < terms[j].Key
+	})
+
+	g := &Glossary{Terms: terms, byKey: make(map[string]int, len(terms))}
+	for i := range terms {
+		g.byKey[terms[i].Key] = i
+	}
+	return g
+}
+
+// Matches 判断词条的写法或释义是否包含 query，比较方式与 Key 相同
+func (t *Term) Matches(query string) bool {
+	q := Key(query)
+	if q == "" {
+		return true
+	}
+	if strings.Contains(t.Key, q) {
+		return true
+	}
+	for _, m := range t.Meanings {
+		if strings.Contains(corpus.Fold(m.Text), q) {
+			return true
+		}
+	}
+	return false
+}
+
+func addType(term *Term, learningType string) {
+	for _, t := range term.Types {
+		if t == learningType {
+			return
+		}
+	}
+	term.Types = append(term.Types, learningType)
+}
+
+func addMeaning(term *Term, meaning string) {
+	folded := corpus.Fold(meaning)
+	for i := range term.Meanings {
+		if corpus.Fold(term.Meanings[i].Text) == folded {
+			term.Meanings[i].Count++
+			return
+		}
+	}
+	term.Meanings = append(term.Meanings, Meaning{Text: meaning, Count: 1})
+}
+
+// spellingCount 记录同一词语的各种写法及出现次数，保留首次出现的顺序
+type spellingCount struct {
+	order  []string
+	counts map[string]int
+}
+
+func (s *spellingCount) add(word string) {
+	if s.counts[word] == 0 {
+		s.order = append(s.order, word)
+	}
+	s.counts[word]++
+}
+
+// mostCommon 返回出现次数最多的写法，次数相同时取先出现的
+func (s *spellingCount) mostCommon() string {
+	best := ""
+	for _, word := range s.order {
+		if best == "" || s.counts[word] > s.counts[best] {
+			best = word
+		}
+	}
+	return best
+}
+
+// sentenceOf 返回原文中包含该词语的句子，找不到时返回原文开头
+func sentenceOf(content string, word string) string {
+	body := strings.TrimSpace(strings.SplitN(content, "——", 2)[0])
+	sentences := strings.FieldsFunc(body, func(r rune) bool {
+		return strings.ContainsRune("。！？；\n", r)
+	})
+	lowerWord := strings.ToLower(notePattern.ReplaceAllString(word, ""))
+	for _, s := range sentences {
+		if strings.Contains(strings.ToLower(s), lowerWord) {
+			return clip(strings.TrimSpace(s))
+		}
+	}
+	return clip(body)
+}
+
+func clip(s string) string {
+	if utf8.RuneCountInString(s) <= maxContextRunes {
+		return s
+	}
+	return string([]rune(s)[:maxContextRunes]) + "…"
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminUsage 按天、按月汇总各类型的 token 用量，并返回预算使用情况
+func (h *Handler) AdminUsage(c *gin.Context) {
+	days := queryInt(c, "days", 30, 1, 366)
+	months := queryInt(c, "months", 12, 1, 60)
+
+	daily, monthly, err := database.GetUsageReport(c.Request.Context(), days, months)
+	if err != nil {
+		log.Printf("获取用量统计失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "获取用量统计失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	budget, err := h.generator.BudgetStatus(c.Request.Context())
+	if err != nil {
+		log.Printf("获取预算状态失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取用量统计成功",
+		Data: models.UsageData{
+			Daily:   daily,
+			Monthly: monthly,
+			Budget:  budget,
+		},
+	})
+}
+
+// AdminListAttempts 查看最近的模型调用记录，可按 type、purpose 筛选
+func (h *Handler) AdminListAttempts(c *gin.Context) {
+	limit := queryInt(c, "limit", 20, 1, 200)
+
+	attempts, err := database.ListGenerationAttempts(c.Request.Context(), c.Query("type"), c.Query("purpose"), limit)
+	if err != nil {
+		log.Printf("获取生成记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "获取生成记录失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取生成记录成功",
+		Data:    attempts,
+	})
+}
+
+// AdminGetAttempt 查看一次模型调用的完整记录，包括原始响应和推理过程
+func (h *Handler) AdminGetAttempt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的记录ID",
+			ErrorCode: "INVALID_ID",
+		})
+		return
+	}
+
+	attempt, err := database.GetGenerationAttempt(c.Request.Context(), uint(id))
+	if err != nil {
+		log.Printf("获取生成记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "获取生成记录失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+	if attempt == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "生成记录不存在",
+			ErrorCode: "NOT_FOUND",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取生成记录成功",
+		Data:    attempt,
+	})
+}
+
+// queryInt 读取整数查询参数，缺省或越界时使用默认值
+func queryInt(c *gin.Context, name string, def int, min int, max int) int {
+	v, err := strconv.Atoi(c.Query(name))
+	if err != nil || v < min || v > max {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListAudit 查看审计日志，可按 entity、entity_id、actor、action 和时间范围 since、until 筛选，
+// 时间格式为 2006-01-02 或 RFC3339
+func (h *Handler) AdminListAudit(c *gin.Context) {
+	query := models.AuditQuery{
+		Entity: c.Query("entity"),
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Limit:  queryInt(c, "limit", 50, 1, 500),
+	}
+
+	var errs []string
+	if v := c.Query("entity_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			errs = append(errs, "entity_id 必须是正整数")
+		}
+		query.EntityID = uint(id)
+	}
+	var err error
+	if query.Since, err = queryTime(c, "since"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if query.Until, err = queryTime(c, "until"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	events, err := database.ListAuditEvents(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取审计日志失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取审计日志成功",
+		Data:    events,
+	})
+}
+
+// AdminRestoreAudit 把数据恢复为某条审计日志中的快照，默认恢复变更前的内容
+func (h *Handler) AdminRestoreAudit(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.RestoreRequest
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+	if req.Snapshot != "" && req.Snapshot != "before" && req.Snapshot != "after" {
+		validationError(c, []string{"snapshot 只能是 before 或 after"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	event, err := database.GetAuditEvent(ctx, id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取审计日志失败")
+		return
+	}
+	if event == nil {
+		notFound(c, "审计日志不存在")
+		return
+	}
+
+	change := adminChange(c)
+	if change.Reason == "" {
+		change.Reason = "恢复审计日志 #" + strconv.FormatUint(uint64(event.ID), 10)
+	}
+
+	restored, err := database.RestoreFromAudit(ctx, change, event, req.Snapshot == "after")
+	if err == database.ErrNothingToRestore || err == database.ErrUnsupportedEntity {
+		validationError(c, []string{err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "恢复数据失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "恢复数据成功",
+		Data:    restored,
+	})
+}
+
+// queryTime 读取时间查询参数，缺省时返回零值
+func queryTime(c *gin.Context, name string) (time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s 的格式应为 2006-01-02 或 RFC3339", name)
+	}
+	return t, nil
+}
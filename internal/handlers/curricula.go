@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/curriculum"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurricula 列出学习路径及进度，可按 type 筛选
+func (h *Handler) GetCurricula(c *gin.Context) {
+	progress, err := database.ListCurriculumProgress(c.Request.Context())
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习路径失败")
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	items := []models.CurriculumData{}
+	for _, path := range curriculum.ForType(c.Query("type")) {
+		p := progress[path.Name]
+		items = append(items, newCurriculumData(&path, &p, today, false))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习路径成功",
+		Data:    items,
+	})
+}
+
+// GetCurriculum 返回学习路径的全部单元和每个单元的学习状态
+func (h *Handler) GetCurriculum(c *gin.Context) {
+	path, progress, ok := h.loadCurriculum(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习路径成功",
+		Data:    newCurriculumData(path, progress, time.Now().Format("2006-01-02"), true),
+	})
+}
+
+// AdminActivateCurriculum 把学习路径设为其类型当前启用的路径，同类型的其他路径随之停用
+func (h *Handler) AdminActivateCurriculum(c *gin.Context) {
+	path, ok := curriculum.Get(c.Param("name"))
+	if !ok {
+		notFound(c, "学习路径不存在")
+		return
+	}
+
+	progress, err := database.ActivateCurriculum(c.Request.Context(), adminChange(c), path.Type, path.Name)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "启用学习路径失败")
+		return
+	}
+
+	log.Printf("🧭 %s 启用学习路径「%s」", models.GetLearningTypeName(path.Type), path.Title)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "启用学习路径成功",
+		Data:    newCurriculumData(path, progress, time.Now().Format("2006-01-02"), false),
+	})
+}
+
+// AdminDeactivateCurriculum 停用学习路径，进度保留，该类型恢复自由生成
+func (h *Handler) AdminDeactivateCurriculum(c *gin.Context) {
+	path, ok := curriculum.Get(c.Param("name"))
+	if !ok {
+		notFound(c, "学习路径不存在")
+		return
+	}
+
+	progress, err := database.GetActiveCurriculum(c.Request.Context(), path.Type)
+	if err == nil && progress != nil && progress.Path == path.Name {
+		_, err = database.ActivateCurriculum(c.Request.Context(), adminChange(c), path.Type, "")
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "停用学习路径失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "停用学习路径成功",
+		Data:    gin.H{"name": path.Name},
+	})
+}
+
+// AdminSetCurriculumProgress 调整学习路径的进度，position 为已学完的单元数
+func (h *Handler) AdminSetCurriculumProgress(c *gin.Context) {
+	path, ok := curriculum.Get(c.Param("name"))
+	if !ok {
+		notFound(c, "学习路径不存在")
+		return
+	}
+
+	var req models.CurriculumProgressRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if *req.Position < 0 || *req.Position > len(path.Units) {
+		validationError(c, []string{fmt.Sprintf("position 应在 0 到 %d 之间", len(path.Units))})
+		return
+	}
+
+	progress, err := database.SetCurriculumPosition(c.Request.Context(), adminChange(c), path.Type, path.Name, *req.Position)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "更新学习路径进度失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "更新学习路径进度成功",
+		Data:    newCurriculumData(path, progress, time.Now().Format("2006-01-02"), false),
+	})
+}
+
+func (h *Handler) loadCurriculum(c *gin.Context) (*curriculum.Path, *models.CurriculumProgress, bool) {
+	path, ok := curriculum.Get(c.Param("name"))
+	if !ok {
+		notFound(c, "学习路径不存在")
+		return nil, nil, false
+	}
+
+	progress, err := database.ListCurriculumProgress(c.Request.Context())
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习路径失败")
+		return nil, nil, false
+	}
+	p := progress[path.Name]
+	return path, &p, true
+}
+
+// newCurriculumData 组合学习路径和进度，withUnits 为 true 时附带各单元及其状态
+func newCurriculumData(path *curriculum.Path, progress *models.CurriculumProgress, today string, withUnits bool) models.CurriculumData {
+	next := progress.NextUnit(today, len(path.Units))
+	data := models.CurriculumData{
+		Name:        path.Name,
+		Type:        path.Type,
+		TypeName:    models.GetLearningTypeName(path.Type),
+		Title:       path.Title,
+		Description: path.Description,
+		Active:      progress.Active,
+		Total:       len(path.Units),
+		Position:    progress.Position,
+		NextUnit:    next,
+		LastDate:    progress.LastDate,
+	}
+	if !withUnits {
+		return data
+	}
+
+	for i, unit := range path.Units {
+		seq := i + 1
+		status := "pending"
+		switch {
+		case seq == progress.Position && progress.LastDate == today:
+			status = "today"
+		case seq <= progress.Position:
+			status = "done"
+		case seq == next:
+			status = "next"
+		}
+		data.Units = append(data.Units, models.CurriculumUnitItem{
+			Seq:    seq,
+			Title:  unit.Title,
+			Topic:  unit.Topic,
+			Text:   unit.Text,
+			Source: unit.Source,
+			Status: status,
+		})
+	}
+	return data
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitFeedback 记录用户对今日内容是否有帮助的反馈，用于比较提示词版本
+func (h *Handler) SubmitFeedback(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return
+	}
+
+	var req models.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "请求参数错误",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{err.Error()},
+		})
+		return
+	}
+
+	record, err := database.GetTodayLearningRecord(c.Request.Context(), learningType)
+	if err != nil {
+		log.Printf("获取今日学习记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "获取今日学习记录失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+	if record == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "今日内容尚未生成",
+			ErrorCode: "NOT_FOUND",
+		})
+		return
+	}
+
+	// 登录用户按用户去重，匿名访问按客户端IP去重，重复提交只会改写自己之前的反馈
+	voter := "ip:" + c.ClientIP()
+	if userID, ok := middleware.UserID(c); ok {
+		voter = fmt.Sprintf("user:%d", userID)
+	}
+	feedback := models.Feedback{
+		RecordID: record.ID,
+		Type:     learningType,
+		Helpful:  *req.Helpful,
+		Voter:    voter,
+	}
+	if err := database.SaveFeedback(c.Request.Context(), &feedback); err != nil {
+		log.Printf("%v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "保存反馈失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "感谢你的反馈",
+	})
+}
+
+// AdminListExperiments 按学习类型展示各提示词版本的调用、解析失败、重复和反馈数据
+func (h *Handler) AdminListExperiments(c *gin.Context) {
+	var result []models.ExperimentData
+	for _, learningType := range models.GetAllLearningTypes() {
+		data, err := h.experimentData(c, learningType)
+		if err != nil {
+			log.Printf("获取实验数据失败: %v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "获取实验数据失败",
+				ErrorCode: "SERVER_ERROR",
+			})
+			return
+		}
+		result = append(result, *data)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取实验数据成功",
+		Data:    result,
+	})
+}
+
+func (h *Handler) experimentData(c *gin.Context, learningType string) (*models.ExperimentData, error) {
+	ctx := c.Request.Context()
+	stats, err := database.GetVariantStats(ctx, learningType)
+	if err != nil {
+		return nil, err
+	}
+	promoted, err := database.GetPromotedVariant(ctx, learningType)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.ExperimentData{
+		Type:     learningType,
+		TypeName: models.GetLearningTypeName(learningType),
+		Promoted: promoted,
+	}
+
+	// 先按配置顺序列出当前版本，再列出已从配置中移除但仍有历史数据的版本；
+	// 未参与实验的调用和本地语料产出的记录不计入
+	delete(stats, "")
+	for _, v := range h.generator.Profile(learningType).Variants {
+		s := stats[v.Name]
+		if s == nil {
+			s = &models.VariantStats{Variant: v.Name}
+		}
+		s.Weight = v.Weight
+		data.Variants = append(data.Variants, *s)
+		delete(stats, v.Name)
+	}
+	var rest []string
+	for name := range stats {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		data.Variants = append(data.Variants, *stats[name])
+	}
+
+	for i := range data.Variants {
+		s := &data.Variants[i]
+		if s.Calls > 0 {
+			s.ParseErrorRate = float64(s.ParseErrors) / float64(s.Calls)
+			s.DuplicateRate = float64(s.Duplicates) / float64(s.Calls)
+		}
+		if total := s.Helpful + s.Unhelpful; total > 0 {
+			s.HelpfulRate = float64(s.Helpful) / float64(total)
+		}
+	}
+	return data, nil
+}
+
+// AdminPromoteVariant 把某个版本提升为该类型唯一使用的提示词，不再按权重分流
+func (h *Handler) AdminPromoteVariant(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	var req models.PromoteVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "请求参数错误",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{err.Error()},
+		})
+		return
+	}
+	if _, ok := h.generator.Profile(learningType).Variant(req.Variant); !ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "配置中不存在该提示词版本",
+			ErrorCode: "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if err := database.PromoteVariant(c.Request.Context(), learningType, req.Variant); err != nil {
+		log.Printf("%v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "提升实验版本失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	log.Printf("🏆 %s 提示词版本 %s 已提升为唯一版本", models.GetLearningTypeName(learningType), req.Variant)
+	data, err := h.experimentData(c, learningType)
+	if err != nil {
+		log.Printf("获取实验数据失败: %v", err)
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "已提升实验版本",
+		Data:    data,
+	})
+}
+
+// AdminClearPromotion 取消提升，恢复按权重分流
+func (h *Handler) AdminClearPromotion(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	if err := database.ClearPromotedVariant(c.Request.Context(), learningType); err != nil {
+		log.Printf("%v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "取消提升失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "已恢复按权重分流",
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/glossary"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGlossary 返回汇总了全部公开记录关键词的词汇表，同一词语在不同记录中的写法合并为一个词条；
+// q 搜索词语和释义，type 按学习类型筛选
+func (h *Handler) GetGlossary(c *gin.Context) {
+	g, ok := h.loadGlossary(c)
+	if !ok {
+		return
+	}
+
+	query := c.Query("q")
+	var matched []*glossary.Term
+	for i := range g.Terms {
+		if g.Terms[i].Matches(query) {
+			matched = append(matched, &g.Terms[i])
+		}
+	}
+
+	limit := queryInt(c, "limit", 50, 1, 500)
+	offset := queryInt(c, "offset", 0, 0, 1<<30)
+	data := models.GlossaryListData{Terms: []models.GlossaryTermItem{}, Total: len(matched)}
+	for i := offset; i < len(matched) && i < offset+limit; i++ {
+		term := matched[i]
+		item := models.GlossaryTermItem{
+			Term:     term.Term,
+			Key:      term.Key,
+			Types:    term.Types,
+			Meanings: make([]string, 0, len(term.Meanings)),
+			Records:  len(term.Occurrences),
+		}
+		for _, m := range term.Meanings {
+			item.Meanings = append(item.Meanings, m.Text)
+		}
+		data.Terms = append(data.Terms, item)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取词汇表成功",
+		Data:    data,
+	})
+}
+
+// GetGlossaryTerm 返回一个词条的全部释义，以及出现过的每条记录和所在的原文句子
+func (h *Handler) GetGlossaryTerm(c *gin.Context) {
+	g, ok := h.loadGlossary(c)
+	if !ok {
+		return
+	}
+	term, ok := g.Lookup(glossary.Key(c.Param("term")))
+	if !ok {
+		notFound(c, "词条不存在")
+		return
+	}
+
+	data := models.GlossaryTermData{
+		Term:        term.Term,
+		Key:         term.Key,
+		Types:       term.Types,
+		Meanings:    make([]models.GlossaryMeaning, 0, len(term.Meanings)),
+		Occurrences: make([]models.GlossaryOccurrence, 0, len(term.Occurrences)),
+	}
+	for _, m := range term.Meanings {
+		data.Meanings = append(data.Meanings, models.GlossaryMeaning{Meaning: m.Text, Count: m.Count})
+	}
+	for _, o := range term.Occurrences {
+		data.Occurrences = append(data.Occurrences, models.GlossaryOccurrence{
+			RecordID: o.RecordID,
+			Type:     o.Type,
+			TypeName: models.GetLearningTypeName(o.Type),
+			Date:     o.Date,
+			Word:     o.Word,
+			Meaning:  o.Meaning,
+			Context:  o.Context,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取词条成功",
+		Data:    data,
+	})
+}
+
+// loadGlossary 返回 type 参数对应的词汇表。先查询数据版本，记录没有变化时直接使用缓存，
+// 否则读取公开记录重新合并
+func (h *Handler) loadGlossary(c *gin.Context) (*glossary.Glossary, bool) {
+	learningType := strings.ToLower(c.Query("type"))
+	if learningType != "" && !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	version, err := database.GlossaryVersion(ctx, learningType)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取词汇表失败")
+		return nil, false
+	}
+	g, err := h.glossaries.Get(learningType, version, func() (*glossary.Glossary, error) {
+		records, err := database.ListGlossaryRecords(ctx, learningType)
+		if err != nil {
+			return nil, err
+		}
+		sources := make([]glossary.Source, len(records))
+		for i, record := range records {
+			sources[i] = glossary.Source{
+				RecordID: record.ID,
+				Type:     record.Type,
+				Date:     record.Date.Format("2006-01-02"),
+				Content:  record.Content,
+				KeyWords: record.FormatKeyWords(),
+			}
+		}
+		log.Printf("📖 已重新合并词汇表（%d 条记录）", len(records))
+		return glossary.Build(sources), nil
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取词汇表失败")
+		return nil, false
+	}
+	return g, true
+}
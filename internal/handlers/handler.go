@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
-	"everyday-study-backend/internal/api"
-	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/auth"
 	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/generator"
+	"everyday-study-backend/internal/glossary"
+	"everyday-study-backend/internal/middleware"
 	"everyday-study-backend/internal/models"
 	"fmt"
 	"log"
@@ -18,15 +19,20 @@ import (
 )
 
 type Handler struct {
-	db            *gorm.DB
-	volcanoClient *api.VolcanoClient
+	db        *gorm.DB
+	generator *generator.Generator
+	// 签发和校验用户令牌，未启用用户功能时为 nil
+	tokens *auth.Manager
+	// 按类型缓存的词汇表，记录变化后下次请求时重新合并
+	glossaries *glossary.Cache
 }
 
-func New(db *gorm.DB) *Handler {
-	cfg := config.Load()
+func New(db *gorm.DB, gen *generator.Generator, tokens *auth.Manager) *Handler {
 	return &Handler{
-		db:            db,
-		volcanoClient: api.NewVolcanoClient(cfg),
+		db:         db,
+		generator:  gen,
+		tokens:     tokens,
+		glossaries: glossary.NewCache(),
 	}
 }
 
@@ -59,7 +65,7 @@ func (h *Handler) GetTodayLearning(c *gin.Context) {
 		models.GetLearningTypeName(learningType), 
 		time.Now().Format("2006-01-02 15:04:05"))
 
-	todayRecord, err := database.GetTodayLearningRecord(learningType)
+	todayRecord, err := database.GetTodayLearningRecord(c.Request.Context(), learningType)
 	if err != nil {
 		log.Printf("获取今日学习记录失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -73,246 +79,76 @@ func (h *Handler) GetTodayLearning(c *gin.Context) {
 	if todayRecord != nil {
 		fmt.Printf("🎯 返回今日已缓存的%s内容，记录ID: %d\n", 
 			models.GetLearningTypeName(learningType), todayRecord.ID)
+		recordView(c, todayRecord)
 		c.JSON(http.StatusOK, models.APIResponse{
 			Success: true,
 			Message: "获取今日学习内容成功",
-			Data: models.TodayLearningData{
-				Type:           todayRecord.Type,
-				TypeName:       models.GetLearningTypeName(todayRecord.Type),
-				Content:        todayRecord.Content,
-				Interpretation: todayRecord.Interpretation,
-				KeyWords:       todayRecord.FormatKeyWords(),
-				Date:           todayRecord.Date.Format("2006-01-02"),
-				FromCache:      true,
-			},
+			Data:    newTodayLearningData(todayRecord, true),
 		})
 		return
 	}
 
-	fmt.Printf("🆕 今日尚无%s内容，开始生成新内容...\n", models.GetLearningTypeName(learningType))
-
-	learnedContent, err := database.GetLearnedContent(learningType)
-	if err != nil {
-		log.Printf("获取已学习内容失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success:   false,
-			Message:   "获取已学习内容失败",
-			ErrorCode: "SERVER_ERROR",
-		})
+	if wantsAsync(c) {
+		h.submitGenerationJob(c, learningType)
 		return
 	}
 
-	fmt.Printf("📚 已学习内容数量: %d\n", len(learnedContent))
-
-	aiResponse, err := h.volcanoClient.CallVolcanoAPI(learningType, learnedContent)
-	if err != nil {
-		log.Printf("调用AI API失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success:   false,
-			Message:   fmt.Sprintf("调用AI API失败: %s", err.Error()),
-			ErrorCode: "SERVER_ERROR",
-		})
-		return
-	}
-
-	if len(aiResponse.Choices) == 0 {
-		log.Printf("AI API返回空响应")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success:   false,
-			Message:   "AI API返回空响应",
-			ErrorCode: "SERVER_ERROR",
-		})
-		return
-	}
-
-	content := aiResponse.Choices[0].Message.Content
-	log.Printf("🤖 AI原始响应: %s", content[:min(100, len(content))]+"...")
-
-	parsedContent, err := h.parseAIContent(content, learningType)
-	if err != nil {
-		log.Printf("解析AI内容失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success:   false,
-			Message:   fmt.Sprintf("解析AI内容失败: %s", err.Error()),
-			ErrorCode: "SERVER_ERROR",
-		})
-		return
-	}
-
-	learningContent := models.LearningContent{
-		Type:           models.LearningType(learningType),
-		Content:        parsedContent.Content,
-		Interpretation: parsedContent.Interpretation,
-		KeyWords:       parsedContent.KeyWords,
-		Date:           time.Now(),
-	}
-
-	log.Printf("📝 创建的学习内容: %+v", learningContent)
+	fmt.Printf("🆕 今日尚无%s内容，开始生成新内容...\n", models.GetLearningTypeName(learningType))
 
-	savedRecord, err := database.SaveLearningRecord(learningType, learningContent)
+	savedRecord, err := h.generator.Generate(c.Request.Context(), learningType)
 	if err != nil {
-		log.Printf("保存学习记录失败: %v", err)
+		if c.Request.Context().Err() != nil {
+			log.Printf("客户端已断开，%s内容将在后台继续生成", models.GetLearningTypeName(learningType))
+			return
+		}
+		log.Printf("生成学习内容失败: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Message:   fmt.Sprintf("保存学习记录失败: %s", err.Error()),
+			Message:   fmt.Sprintf("生成学习内容失败: %s", err.Error()),
 			ErrorCode: "SERVER_ERROR",
 		})
 		return
 	}
 
 	fmt.Printf("✅ 成功保存%s学习记录, ID: %d\n", models.GetLearningTypeName(learningType), savedRecord.ID)
+	recordView(c, savedRecord)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "获取今日学习内容成功",
-		Data: models.TodayLearningData{
-			Type:           savedRecord.Type,
-			TypeName:       models.GetLearningTypeName(savedRecord.Type),
-			Content:        savedRecord.Content,
-			Interpretation: savedRecord.Interpretation,
-			KeyWords:       savedRecord.FormatKeyWords(),
-			Date:           savedRecord.Date.Format("2006-01-02"),
-			FromCache:      false,
-		},
+		Data:    newTodayLearningData(savedRecord, false),
 	})
 }
 
-type ParsedContent struct {
-	Content        string
-	Interpretation string
-	KeyWords       []string
-}
-
-func (h *Handler) parseAIContent(contentStr string, learningType string) (*ParsedContent, error) {
-	contentStr = strings.TrimPrefix(contentStr, "```json")
-	contentStr = strings.TrimSuffix(contentStr, "```")
-	contentStr = strings.TrimSpace(contentStr)
-
-	var aiData models.AIContent
-	err := json.Unmarshal([]byte(contentStr), &aiData)
-	if err == nil {
-		result := h.extractContentFromAIData(&aiData, learningType)
-		if result != nil {
-			return result, nil
-		}
+func newTodayLearningData(record *models.LearningRecord, fromCache bool) models.TodayLearningData {
+	return models.TodayLearningData{
+		ID:             record.ID,
+		Type:           record.Type,
+		TypeName:       models.GetLearningTypeName(record.Type),
+		Content:        record.Content,
+		Interpretation: record.Interpretation,
+		KeyWords:       record.FormatKeyWords(),
+		SourceID:       record.SourceID,
+		Verification:   record.Verification,
+		Theme:          record.Theme,
+		Curriculum:     record.Curriculum,
+		Unit:           record.Unit,
+		Date:           record.Date.Format("2006-01-02"),
+		FromCache:      fromCache,
 	}
-
-	log.Printf("直接解析失败，尝试灵活解析: %v", err)
-
-	return h.flexibleParseContent(contentStr, learningType)
 }
 
-func (h *Handler) extractContentFromAIData(aiData *models.AIContent, learningType string) *ParsedContent {
-	result := &ParsedContent{
-		Interpretation: aiData.Interpretation,
-		KeyWords:       []string{},
+func newLearningHistoryItem(record *models.LearningRecord) models.LearningHistoryItem {
+	return models.LearningHistoryItem{
+		ID:             record.ID,
+		Type:           record.Type,
+		TypeName:       models.GetLearningTypeName(record.Type),
+		Content:        record.Content,
+		Interpretation: record.Interpretation,
+		KeyWords:       record.FormatKeyWords(),
+		SourceID:       record.SourceID,
+		Date:           record.Date.Format("2006-01-02"),
 	}
-
-	switch strings.ToLower(learningType) {
-	case "english":
-		if aiData.Proverb != "" {
-			result.Content = aiData.Proverb
-		}
-		if len(aiData.KeyWords) > 0 {
-			for _, kw := range aiData.KeyWords {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
-			}
-		}
-	case "chinese":
-		if aiData.Poem != "" {
-			result.Content = aiData.Poem
-		}
-		if len(aiData.KeyWords) > 0 {
-			for _, kw := range aiData.KeyWords {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
-			}
-		}
-	case "tcm":
-		if aiData.TCMText != "" {
-			result.Content = aiData.TCMText
-		}
-		if len(aiData.KeyConcepts) > 0 {
-			for _, kc := range aiData.KeyConcepts {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kc.Concept, kc.Meaning))
-			}
-		}
-	}
-
-	if result.Content == "" || result.Interpretation == "" {
-		return nil
-	}
-
-	return result
-}
-
-func (h *Handler) flexibleParseContent(contentStr string, learningType string) (*ParsedContent, error) {
-	var rawContent map[string]interface{}
-	err := json.Unmarshal([]byte(contentStr), &rawContent)
-	if err != nil {
-		return nil, fmt.Errorf("无法解析JSON内容: %v", err)
-	}
-
-	result := &ParsedContent{
-		KeyWords: []string{},
-	}
-
-	switch strings.ToLower(learningType) {
-	case "english":
-		result.Content = h.getStringValue(rawContent, "proverb")
-		result.Interpretation = h.getStringValue(rawContent, "interpretation")
-		result.KeyWords = h.parseKeyItems(rawContent, "key_words", "word", "meaning")
-
-	case "chinese":
-		result.Content = h.getStringValue(rawContent, "poem")
-		result.Interpretation = h.getStringValue(rawContent, "interpretation")
-		result.KeyWords = h.parseKeyItems(rawContent, "key_words", "word", "meaning")
-
-	case "tcm":
-		result.Content = h.getStringValue(rawContent, "tcm_text")
-		result.Interpretation = h.getStringValue(rawContent, "interpretation")
-		result.KeyWords = h.parseKeyItems(rawContent, "key_concepts", "concept", "meaning")
-	}
-
-	if result.Content == "" {
-		return nil, fmt.Errorf("解析后的主要内容为空")
-	}
-
-	if result.Interpretation == "" {
-		return nil, fmt.Errorf("解析后的释义为空")
-	}
-
-	return result, nil
-}
-
-func (h *Handler) getStringValue(data map[string]interface{}, key string) string {
-	if value, exists := data[key]; exists {
-		if str, ok := value.(string); ok {
-			return str
-		}
-	}
-	return ""
-}
-
-func (h *Handler) parseKeyItems(data map[string]interface{}, arrayKey, itemKey, meaningKey string) []string {
-	var result []string
-
-	if value, exists := data[arrayKey]; exists {
-		if array, ok := value.([]interface{}); ok {
-			for _, item := range array {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					itemValue := h.getStringValue(itemMap, itemKey)
-					meaningValue := h.getStringValue(itemMap, meaningKey)
-					if itemValue != "" && meaningValue != "" {
-						result = append(result, fmt.Sprintf("%s: %s", itemValue, meaningValue))
-					}
-				} else if str, ok := item.(string); ok {
-					result = append(result, str)
-				}
-			}
-		}
-	}
-
-	return result
 }
 
 func (h *Handler) GetLearningHistory(c *gin.Context) {
@@ -323,7 +159,7 @@ func (h *Handler) GetLearningHistory(c *gin.Context) {
 		limit = 10
 	}
 
-	records, err := database.GetLearningHistory("", limit)
+	records, err := h.learningHistory(c, "", limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -335,14 +171,7 @@ func (h *Handler) GetLearningHistory(c *gin.Context) {
 
 	historyItems := make([]models.LearningHistoryItem, len(records))
 	for i, record := range records {
-		historyItems[i] = models.LearningHistoryItem{
-			Type:           record.Type,
-			TypeName:       models.GetLearningTypeName(record.Type),
-			Content:        record.Content,
-			Interpretation: record.Interpretation,
-			KeyWords:       record.FormatKeyWords(),
-			Date:           record.Date.Format("2006-01-02"),
-		}
+		historyItems[i] = newLearningHistoryItem(&record)
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -374,7 +203,7 @@ func (h *Handler) GetLearningHistoryByType(c *gin.Context) {
 		return
 	}
 
-	records, err := database.GetLearningHistory(learningType, limit)
+	records, err := h.learningHistory(c, learningType, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -386,14 +215,7 @@ func (h *Handler) GetLearningHistoryByType(c *gin.Context) {
 
 	historyItems := make([]models.LearningHistoryItem, len(records))
 	for i, record := range records {
-		historyItems[i] = models.LearningHistoryItem{
-			Type:           record.Type,
-			TypeName:       models.GetLearningTypeName(record.Type),
-			Content:        record.Content,
-			Interpretation: record.Interpretation,
-			KeyWords:       record.FormatKeyWords(),
-			Date:           record.Date.Format("2006-01-02"),
-		}
+		historyItems[i] = newLearningHistoryItem(&record)
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -406,8 +228,94 @@ func (h *Handler) GetLearningHistoryByType(c *gin.Context) {
 	})
 }
 
+// GetLearningVersions 返回某一天的全部版本，包括当天被重新生成替换掉的旧版本
+func (h *Handler) GetLearningVersions(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", c.Param("date"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "日期格式应为 2006-01-02",
+			ErrorCode: "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	// 未来日期的内容（管理员提前固定的）不对外展示
+	var records []models.LearningRecord
+	if !day.After(time.Now()) {
+		records, err = database.GetRecordVersions(c.Request.Context(), learningType, day)
+		if err != nil {
+			log.Printf("%v", err)
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "获取历史版本失败",
+				ErrorCode: "SERVER_ERROR",
+			})
+			return
+		}
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "当天没有学习记录",
+			ErrorCode: "NOT_FOUND",
+		})
+		return
+	}
+
+	// 当前展示的版本与今日内容接口一致：固定记录优先，其次是最新的未替换版本
+	var published *models.LearningRecord
+	for i := range records {
+		r := &records[i]
+		if r.Superseded {
+			continue
+		}
+		if published == nil || (r.Pinned && !published.Pinned) || (r.Pinned == published.Pinned && r.Version > published.Version) {
+			published = r
+		}
+	}
+
+	data := models.LearningVersionsData{
+		Type:     learningType,
+		TypeName: models.GetLearningTypeName(learningType),
+		Date:     day.Format("2006-01-02"),
+		Versions: make([]models.LearningVersionItem, len(records)),
+	}
+	if published != nil {
+		data.Published = published.Version
+	}
+	for i, record := range records {
+		data.Versions[i] = models.LearningVersionItem{
+			Version:        record.Version,
+			Published:      published != nil && record.ID == published.ID,
+			Content:        record.Content,
+			Interpretation: record.Interpretation,
+			KeyWords:       record.FormatKeyWords(),
+			SourceID:       record.SourceID,
+			CreatedAt:      record.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取历史版本成功",
+		Data:    data,
+	})
+}
+
 func (h *Handler) GetGlobalStats(c *gin.Context) {
-	stats, err := database.GetGlobalStats()
+	stats, err := database.GetGlobalStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -417,22 +325,21 @@ func (h *Handler) GetGlobalStats(c *gin.Context) {
 		return
 	}
 
+	data := models.UserStatsData{Stats: stats}
+	if userID, ok := middleware.UserID(c); ok {
+		data.User, err = h.userStats(c, userID)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "获取统计信息成功",
-		Data: models.UserStatsData{
-			Stats: stats,
-		},
+		Data:    data,
 	})
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 func (h *Handler) DebugShowAllRecords(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "20")
 	limit, _ := strconv.Atoi(limitStr)
@@ -441,7 +348,7 @@ func (h *Handler) DebugShowAllRecords(c *gin.Context) {
 	}
 
 	var records []models.LearningRecord
-	err := h.db.Order("date DESC").Limit(limit).Find(&records).Error
+	err := h.db.WithContext(c.Request.Context()).Order("date DESC").Limit(limit).Find(&records).Error
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -454,14 +361,19 @@ func (h *Handler) DebugShowAllRecords(c *gin.Context) {
 	debugRecords := make([]gin.H, len(records))
 	for i, record := range records {
 		debugRecords[i] = gin.H{
-			"id":             record.ID,
-			"type":           record.Type,
-			"type_name":      models.GetLearningTypeName(record.Type),
-			"content":        record.Content,
-			"interpretation": record.Interpretation,
-			"key_words":      record.FormatKeyWords(),
-			"date":           record.Date.Format("2006-01-02 15:04:05"),
-			"created_at":     record.CreatedAt.Format("2006-01-02 15:04:05"),
+			"id":                record.ID,
+			"type":              record.Type,
+			"type_name":         models.GetLearningTypeName(record.Type),
+			"content":           record.Content,
+			"interpretation":    record.Interpretation,
+			"key_words":         record.FormatKeyWords(),
+			"source_id":         record.SourceID,
+			"verification":      record.Verification,
+			"verification_note": record.VerificationNote,
+			"attempt_id":        record.AttemptID,
+			"tier":              record.Tier,
+			"date":              record.Date.Format("2006-01-02 15:04:05"),
+			"created_at":        record.CreatedAt.Format("2006-01-02 15:04:05"),
 		}
 	}
 
@@ -487,7 +399,7 @@ func (h *Handler) DebugClearTodayRecords(c *gin.Context) {
 		return
 	}
 
-	database.DebugClearTodayRecords(learningType)
+	database.DebugClearTodayRecords(c.Request.Context(), learningType)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -512,7 +424,16 @@ func (h *Handler) DebugForceGenerateContent(c *gin.Context) {
 		return
 	}
 
-	database.DebugClearTodayRecords(learningType)
+	// 生成新版本替换今日内容，旧版本仍可通过版本历史查看
+	if _, err := h.generator.Regenerate(c.Request.Context(), learningType); err != nil {
+		log.Printf("强制生成 %s 内容失败: %v", learningType, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "生成内容失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
 
 	h.GetTodayLearning(c)
 }
@@ -532,7 +453,7 @@ func (h *Handler) DebugShowLearnedContent(c *gin.Context) {
 	result := make(map[string]interface{})
 
 	if learningType != "" {
-		contents, err := database.GetLearnedContent(learningType)
+		contents, err := database.GetLearnedContent(c.Request.Context(), learningType)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
@@ -550,7 +471,7 @@ func (h *Handler) DebugShowLearnedContent(c *gin.Context) {
 	} else {
 		allTypes := models.GetAllLearningTypes()
 		for _, t := range allTypes {
-			contents, err := database.GetLearnedContent(t)
+			contents, err := database.GetLearnedContent(c.Request.Context(), t)
 			if err != nil {
 				result[t] = gin.H{
 					"type_name": models.GetLearningTypeName(t),
@@ -619,7 +540,7 @@ func (h *Handler) DebugTestAIAPI(c *gin.Context) {
 		return
 	}
 
-	learnedContent, err := database.GetLearnedContent(learningType)
+	learnedContent, err := database.GetLearnedContent(c.Request.Context(), learningType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -634,7 +555,8 @@ func (h *Handler) DebugTestAIAPI(c *gin.Context) {
 		testLearned = testLearned[:5]
 	}
 
-	aiResponse, err := h.volcanoClient.CallVolcanoAPI(learningType, testLearned)
+	settings := h.generator.PrimarySettings(learningType)
+	aiResponse, err := h.generator.Client().CallVolcanoAPI(c.Request.Context(), settings, learningType, testLearned)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
@@ -662,6 +584,7 @@ func (h *Handler) DebugTestAIAPI(c *gin.Context) {
 		Data: gin.H{
 			"type":              learningType,
 			"type_name":         models.GetLearningTypeName(learningType),
+			"model":             settings.Model,
 			"learned_count":     len(testLearned),
 			"test_learned":      testLearned,
 			"ai_raw_response":   content,
@@ -682,27 +605,35 @@ func (h *Handler) DebugTriggerUpdate(c *gin.Context) {
 		return
 	}
 
+	// 今日记录转为旧版本而不是删除，下次访问时生成新版本
+	change := database.Change{Actor: models.ActorDebug, Reason: "调试接口触发更新"}
+	supersede := func(t string) {
+		if _, err := database.SupersedeTodayRecords(c.Request.Context(), change, t); err != nil {
+			log.Printf("替换今日 %s 记录失败: %v", models.GetLearningTypeName(t), err)
+		}
+	}
+
 	if learningType != "" {
-		database.DebugClearTodayRecords(learningType)
+		supersede(learningType)
 		c.JSON(http.StatusOK, models.APIResponse{
 			Success: true,
-			Message: "已清理指定类型的今日记录，请重新访问对应的学习接口",
+			Message: "已将指定类型的今日记录转为旧版本，请重新访问对应的学习接口",
 			Data: gin.H{
 				"type":      learningType,
 				"type_name": models.GetLearningTypeName(learningType),
-				"action":    "清理今日记录",
+				"action":    "替换今日记录",
 				"next_step": "访问 /api/today-learning/" + learningType,
 			},
 		})
 	} else {
 		for _, t := range models.GetAllLearningTypes() {
-			database.DebugClearTodayRecords(t)
+			supersede(t)
 		}
 		c.JSON(http.StatusOK, models.APIResponse{
 			Success: true,
-			Message: "已清理所有类型的今日记录",
+			Message: "已将所有类型的今日记录转为旧版本",
 			Data: gin.H{
-				"action":    "清理所有今日记录",
+				"action":    "替换所有今日记录",
 				"types":     models.GetAllLearningTypes(),
 				"next_step": "重新访问各类型的学习接口将生成新内容",
 			},
@@ -726,7 +657,7 @@ func (h *Handler) DebugSystemStatus(c *gin.Context) {
 
 	todayStatus := make(map[string]gin.H)
 	for _, t := range models.GetAllLearningTypes() {
-		record, err := database.GetTodayLearningRecord(t)
+		record, err := database.GetTodayLearningRecord(c.Request.Context(), t)
 		if err != nil {
 			todayStatus[t] = gin.H{
 				"type_name": models.GetLearningTypeName(t),
@@ -740,12 +671,13 @@ func (h *Handler) DebugSystemStatus(c *gin.Context) {
 				"record":    nil,
 			}
 		} else {
+			content := []rune(record.Content)
 			todayStatus[t] = gin.H{
 				"type_name": models.GetLearningTypeName(t),
 				"status":    "今日已有记录",
 				"record_id": record.ID,
 				"date":      record.Date.Format("2006-01-02 15:04:05"),
-				"content":   record.Content[:min(50, len(record.Content))] + "...",
+				"content":   string(content[:min(50, len(content))]) + "...",
 			}
 		}
 	}
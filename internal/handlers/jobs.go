@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsAsync 判断客户端是否选择异步模式：?async=true 或 Prefer: respond-async
+func wantsAsync(c *gin.Context) bool {
+	switch strings.ToLower(c.Query("async")) {
+	case "1", "true", "yes":
+		return true
+	}
+	return strings.Contains(strings.ToLower(c.GetHeader("Prefer")), "respond-async")
+}
+
+func (h *Handler) submitGenerationJob(c *gin.Context, learningType string) {
+	job, err := h.generator.Submit(c.Request.Context(), learningType)
+	if err != nil {
+		log.Printf("创建生成任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "创建生成任务失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+
+	data := newJobData(job)
+	c.Header("Location", data.StatusURL)
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: "内容生成中，请稍后查询任务状态",
+		Data:    data,
+	})
+}
+
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := database.GetGenerationJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		log.Printf("获取生成任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "获取生成任务失败",
+			ErrorCode: "SERVER_ERROR",
+		})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "任务不存在",
+			ErrorCode: "NOT_FOUND",
+		})
+		return
+	}
+
+	data := newJobData(job)
+	if job.Status == models.JobStatusSucceeded && job.RecordID != nil {
+		record, err := database.GetLearningRecordByID(c.Request.Context(), *job.RecordID)
+		if err != nil {
+			log.Printf("获取任务结果失败: %v", err)
+		} else if record != nil {
+			recordView(c, record)
+			result := newTodayLearningData(record, false)
+			data.Result = &result
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取任务状态成功",
+		Data:    data,
+	})
+}
+
+func newJobData(job *models.GenerationJob) models.JobData {
+	return models.JobData{
+		JobID:     job.ID,
+		Type:      job.Type,
+		TypeName:  models.GetLearningTypeName(job.Type),
+		Status:    job.Status,
+		StatusURL: "/api/jobs/" + job.ID,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 笔记最多保留的字符数
+const maxNoteRunes = 5000
+
+// GetMyRecord 返回当前用户对某条学习记录的学习状态
+func (h *Handler) GetMyRecord(c *gin.Context) {
+	record, ok := h.loadVisibleRecord(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.UserID(c)
+	userRecord, err := database.GetUserRecord(c.Request.Context(), userID, record.ID)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习状态失败")
+		return
+	}
+	if userRecord == nil {
+		userRecord = &models.UserRecord{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习状态成功",
+		Data:    newUserRecordData(userRecord, record),
+	})
+}
+
+func (h *Handler) MarkStudied(c *gin.Context) {
+	h.updateMyRecord(c, "已标记为学会", func(ur *models.UserRecord) {
+		if !ur.Studied {
+			now := time.Now()
+			ur.Studied, ur.StudiedAt = true, &now
+		}
+	})
+}
+
+func (h *Handler) UnmarkStudied(c *gin.Context) {
+	h.updateMyRecord(c, "已取消学会标记", func(ur *models.UserRecord) {
+		ur.Studied, ur.StudiedAt = false, nil
+	})
+}
+
+func (h *Handler) AddFavorite(c *gin.Context) {
+	h.updateMyRecord(c, "已收藏", func(ur *models.UserRecord) {
+		if !ur.Favorite {
+			now := time.Now()
+			ur.Favorite, ur.FavoritedAt = true, &now
+		}
+	})
+}
+
+func (h *Handler) RemoveFavorite(c *gin.Context) {
+	h.updateMyRecord(c, "已取消收藏", func(ur *models.UserRecord) {
+		ur.Favorite, ur.FavoritedAt = false, nil
+	})
+}
+
+// SaveNote 保存当前用户对某条学习记录的笔记，覆盖原有笔记
+func (h *Handler) SaveNote(c *gin.Context) {
+	var req models.NoteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" || utf8.RuneCountInString(note) > maxNoteRunes {
+		validationError(c, []string{fmt.Sprintf("笔记不能为空，且不超过 %d 个字符", maxNoteRunes)})
+		return
+	}
+
+	h.updateMyRecord(c, "笔记已保存", func(ur *models.UserRecord) {
+		now := time.Now()
+		ur.Note, ur.NoteUpdatedAt = note, &now
+	})
+}
+
+func (h *Handler) DeleteNote(c *gin.Context) {
+	h.updateMyRecord(c, "笔记已删除", func(ur *models.UserRecord) {
+		ur.Note, ur.NoteUpdatedAt = "", nil
+	})
+}
+
+// ListFavorites 返回当前用户的收藏，q 同时搜索内容、释义、关键词和笔记
+func (h *Handler) ListFavorites(c *gin.Context) {
+	h.listMyRecords(c, "获取收藏成功", models.UserRecordQuery{Favorite: true})
+}
+
+// ListNotes 返回当前用户写过笔记的记录，q 同时搜索内容、释义、关键词和笔记
+func (h *Handler) ListNotes(c *gin.Context) {
+	h.listMyRecords(c, "获取笔记成功", models.UserRecordQuery{HasNote: true})
+}
+
+// userStats 汇总用户按类型的学习状态、今天待复习的数量和练习正确率
+func (h *Handler) userStats(c *gin.Context, userID uint) (map[string]models.UserTypeStats, error) {
+	stats, err := database.GetUserStats(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	due, err := database.CountDueReviews(c.Request.Context(), userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for t, n := range due {
+		s := stats[t]
+		s.TypeName = models.GetLearningTypeName(t)
+		s.DueReviews = n
+		stats[t] = s
+	}
+	quizzes, err := database.GetQuizStats(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	for t, q := range quizzes {
+		s := stats[t]
+		s.TypeName = models.GetLearningTypeName(t)
+		s.Quizzes = q.Quizzes
+		if q.Total > 0 {
+			s.QuizAccuracy = math.Round(float64(q.Correct)/float64(q.Total)*1000) / 1000
+		}
+		stats[t] = s
+	}
+	return stats, nil
+}
+
+func (h *Handler) listMyRecords(c *gin.Context, message string, q models.UserRecordQuery) {
+	q.Type = strings.ToLower(c.Query("type"))
+	q.Query = c.Query("q")
+	q.Limit = queryInt(c, "limit", 20, 1, 200)
+	q.Offset = queryInt(c, "offset", 0, 0, 1<<30)
+
+	userID, _ := middleware.UserID(c)
+	userRecords, records, total, err := database.ListUserRecords(c.Request.Context(), userID, q)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习状态失败")
+		return
+	}
+
+	items := make([]models.UserRecordData, 0, len(userRecords))
+	for i := range userRecords {
+		record := records[userRecords[i].RecordID]
+		items = append(items, newUserRecordData(&userRecords[i], &record))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    models.UserRecordListData{Total: total, Items: items},
+	})
+}
+
+// updateMyRecord 修改当前用户对路径参数中学习记录的学习状态并返回结果
+func (h *Handler) updateMyRecord(c *gin.Context, message string, apply func(*models.UserRecord)) {
+	record, ok := h.loadVisibleRecord(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.UserID(c)
+	userRecord, err := database.UpdateUserRecord(c.Request.Context(), userID, record, apply)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "保存学习状态失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    newUserRecordData(userRecord, record),
+	})
+}
+
+func (h *Handler) loadVisibleRecord(c *gin.Context) (*models.LearningRecord, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+
+	record, err := database.GetVisibleRecord(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习记录失败")
+		return nil, false
+	}
+	if record == nil {
+		notFound(c, "学习记录不存在")
+		return nil, false
+	}
+	return record, true
+}
+
+func newUserRecordData(userRecord *models.UserRecord, record *models.LearningRecord) models.UserRecordData {
+	data := models.UserRecordData{
+		Record:        newLearningHistoryItem(record),
+		Studied:       userRecord.Studied,
+		StudiedAt:     userRecord.StudiedAt,
+		Favorite:      userRecord.Favorite,
+		FavoritedAt:   userRecord.FavoritedAt,
+		Note:          userRecord.Note,
+		NoteUpdatedAt: userRecord.NoteUpdatedAt,
+	}
+	if !userRecord.LastSeenAt.IsZero() {
+		data.LastSeenAt = &userRecord.LastSeenAt
+	}
+	return data
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/calendar"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListOverrides 查看预设，可按 type 和日期范围 from、to 筛选
+func (h *Handler) AdminListOverrides(c *gin.Context) {
+	overrides, err := database.ListOverrides(c.Request.Context(), strings.ToLower(c.Query("type")), c.Query("from"), c.Query("to"))
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取预设列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取预设列表成功",
+		Data:    overrides,
+	})
+}
+
+// AdminCreateOverride 为某类型某一天预设内容或主题，同一类型同一天只能有一条预设
+func (h *Handler) AdminCreateOverride(c *gin.Context) {
+	var req models.OverrideRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var override models.Override
+	if errs := applyOverrideRequest(&override, req); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+	if !h.checkOverrideConflict(c, &override) {
+		return
+	}
+
+	if err := database.CreateOverride(c.Request.Context(), adminChange(c), &override); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "创建预设失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "创建预设成功",
+		Data:    override,
+	})
+}
+
+func (h *Handler) AdminUpdateOverride(c *gin.Context) {
+	override, ok := h.loadOverride(c)
+	if !ok {
+		return
+	}
+
+	var req models.OverrideRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	before := *override
+	if errs := applyOverrideRequest(override, req); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+	if !h.checkOverrideConflict(c, override) {
+		return
+	}
+
+	if err := database.UpdateOverride(c.Request.Context(), adminChange(c), before, override); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "更新预设失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "更新预设成功",
+		Data:    override,
+	})
+}
+
+func (h *Handler) AdminDeleteOverride(c *gin.Context) {
+	override, ok := h.loadOverride(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DeleteOverride(c.Request.Context(), adminChange(c), override); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "删除预设失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除预设成功",
+		Data:    gin.H{"id": override.ID},
+	})
+}
+
+// AdminCalendar 列出某年的节气、传统节日和已有预设，便于提前安排内容
+func (h *Handler) AdminCalendar(c *gin.Context) {
+	year := queryInt(c, "year", time.Now().Year(), 1900, 2100)
+
+	overrides, err := database.ListOverrides(c.Request.Context(), "", fmt.Sprintf("%d-01-01", year), fmt.Sprintf("%d-12-31", year))
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取预设列表失败")
+		return
+	}
+
+	var days []models.CalendarDay
+	index := make(map[string]int)
+	dayOf := func(date string) *models.CalendarDay {
+		if i, ok := index[date]; ok {
+			return &days[i]
+		}
+		index[date] = len(days)
+		days = append(days, models.CalendarDay{Date: date})
+		return &days[len(days)-1]
+	}
+	for _, o := range calendar.Occasions(year) {
+		day := dayOf(o.Date)
+		day.Occasions = append(day.Occasions, o.Name)
+	}
+	for _, o := range overrides {
+		day := dayOf(o.Date)
+		day.Overrides = append(day.Overrides, o)
+	}
+	sortCalendarDays(days)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取日历成功",
+		Data:    days,
+	})
+}
+
+func sortCalendarDays(days []models.CalendarDay) {
+	for i := 1; i < len(days); i++ {
+		for j := i; j > 0 && days[j].Date < days[j-1].Date; j-- {
+			days[j], days[j-1] = days[j-1], days[j]
+		}
+	}
+}
+
+func (h *Handler) loadOverride(c *gin.Context) (*models.Override, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+
+	override, err := database.GetOverrideByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取预设失败")
+		return nil, false
+	}
+	if override == nil {
+		notFound(c, "预设不存在")
+		return nil, false
+	}
+	return override, true
+}
+
+// checkOverrideConflict 检查同一类型同一天是否已有其他预设，有冲突时返回 409
+func (h *Handler) checkOverrideConflict(c *gin.Context, override *models.Override) bool {
+	day, _ := time.ParseInLocation("2006-01-02", override.Date, time.Local)
+	existing, err := database.GetOverride(c.Request.Context(), override.Type, day)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取预设失败")
+		return false
+	}
+	if existing != nil && existing.ID != override.ID {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Message:   "该类型当天已有预设",
+			ErrorCode: "CONFLICT",
+			Data:      existing,
+		})
+		return false
+	}
+	return true
+}
+
+// applyOverrideRequest 写入并校验预设：给出内容时释义和关键词必填，否则必须给出主题；日期不能早于今天
+func applyOverrideRequest(override *models.Override, req models.OverrideRequest) []string {
+	override.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	override.Date = strings.TrimSpace(req.Date)
+	override.Content = strings.TrimSpace(req.Content)
+	override.Interpretation = strings.TrimSpace(req.Interpretation)
+	override.KeyWords = (&models.LearningContent{KeyWords: req.KeyWords}).FormatKeyWords()
+	override.Theme = strings.TrimSpace(req.Theme)
+	override.Note = strings.TrimSpace(req.Note)
+
+	var errs []string
+	if !models.IsValidLearningType(override.Type) {
+		errs = append(errs, fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", ")))
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", override.Date, time.Local)
+	if err != nil {
+		errs = append(errs, "日期格式应为 2006-01-02")
+	} else if now := time.Now(); day.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+		errs = append(errs, "日期不能早于今天")
+	}
+
+	if override.HasContent() {
+		content := models.LearningContent{
+			Content:        override.Content,
+			Interpretation: override.Interpretation,
+			KeyWords:       req.KeyWords,
+		}
+		errs = append(errs, content.Validate()...)
+	} else if override.Theme == "" {
+		errs = append(errs, "内容和主题至少填写一项")
+	}
+	return errs
+}
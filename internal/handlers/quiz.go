@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"everyday-study-backend/internal/quiz"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuiz 返回某一天学习内容的练习题。题目在本地根据内容和关键词生成，
+// 同一条记录的题目固定不变；model=true 时附带模型出的题目，首次请求时生成并缓存
+func (h *Handler) GetQuiz(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", c.Param("date"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "日期格式应为 2006-01-02",
+			ErrorCode: "VALIDATION_ERROR",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var record *models.LearningRecord
+	if !day.After(time.Now()) {
+		record, err = database.GetPublishedRecord(ctx, learningType, day)
+		if err != nil {
+			log.Printf("%v", err)
+			serverError(c, "获取练习题失败")
+			return
+		}
+	}
+	if record == nil {
+		notFound(c, "当天没有学习记录")
+		return
+	}
+
+	saved, questions, err := loadQuiz(ctx, record)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取练习题失败")
+		return
+	}
+
+	message := "获取练习题成功"
+	withModel := c.Query("model") == "true"
+	if withModel {
+		modelQuestions, err := h.modelQuestions(ctx, saved, record)
+		if err != nil {
+			log.Printf("⚠️  模型出题失败: %v", err)
+			message = "模型出题失败，仅返回本地生成的题目"
+			withModel = false
+		}
+		questions = append(questions, modelQuestions...)
+	}
+
+	data := models.QuizData{
+		ID:        saved.ID,
+		RecordID:  record.ID,
+		Type:      record.Type,
+		TypeName:  models.GetLearningTypeName(record.Type),
+		Date:      record.Date.Format("2006-01-02"),
+		Questions: make([]models.QuizQuestionItem, 0, len(questions)),
+		Model:     withModel,
+	}
+	for _, q := range questions {
+		data.Questions = append(data.Questions, models.QuizQuestionItem{
+			ID:      q.ID,
+			Kind:    q.Kind,
+			Prompt:  q.Prompt,
+			Options: q.Options,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// loadQuiz 返回学习记录当前版本的本地题目。还没有出过题或记录在出题后被修改过时另出一份新题目，
+// 新题目有新的ID，按旧题目提交的答案不受影响
+func loadQuiz(ctx context.Context, record *models.LearningRecord) (*models.Quiz, []quiz.Question, error) {
+	saved, err := database.GetQuizByRecord(ctx, record.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if saved != nil && saved.RecordUpdatedAt.Equal(record.UpdatedAt) {
+		questions, err := decodeQuestions(saved.Questions)
+		if err != nil {
+			return nil, nil, err
+		}
+		return saved, questions, nil
+	}
+
+	pool, err := database.ListQuizPool(ctx, record.Type, record.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	materials := make([]quiz.Material, len(pool))
+	for i := range pool {
+		materials[i] = quizMaterial(&pool[i])
+	}
+	questions := quiz.Build(quizMaterial(record), materials)
+
+	encoded, err := json.Marshal(questions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码练习题失败: %v", err)
+	}
+	saved = &models.Quiz{
+		RecordID:        record.ID,
+		Type:            record.Type,
+		RecordUpdatedAt: record.UpdatedAt,
+		Questions:       string(encoded),
+	}
+	if err := database.SaveQuiz(ctx, saved); err != nil {
+		return nil, nil, err
+	}
+	// 并发请求先保存了同一版本的题目时以已保存的为准
+	if saved.Questions != string(encoded) {
+		if questions, err = decodeQuestions(saved.Questions); err != nil {
+			return nil, nil, err
+		}
+	}
+	log.Printf("📝 已为记录 %d（练习 %d）出题 %d 道", record.ID, saved.ID, len(questions))
+	return saved, questions, nil
+}
+
+// modelQuestions 返回缓存的模型题目，没有缓存时请模型出题
+func (h *Handler) modelQuestions(ctx context.Context, saved *models.Quiz, record *models.LearningRecord) ([]quiz.Question, error) {
+	if saved.ModelQuestions != "" {
+		return decodeQuestions(saved.ModelQuestions)
+	}
+
+	questions, attemptID, err := h.generator.QuizQuestions(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(questions)
+	if err != nil {
+		return nil, fmt.Errorf("编码练习题失败: %v", err)
+	}
+	saved.ModelQuestions = string(encoded)
+	saved.ModelAttemptID = attemptID
+	if err := database.SaveModelQuestions(ctx, saved); err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func decodeQuestions(encoded string) ([]quiz.Question, error) {
+	var questions []quiz.Question
+	if encoded == "" {
+		return questions, nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &questions); err != nil {
+		return nil, fmt.Errorf("解析练习题失败: %v", err)
+	}
+	return questions, nil
+}
+
+func quizMaterial(record *models.LearningRecord) quiz.Material {
+	return quiz.Material{
+		ID:             record.ID,
+		Type:           record.Type,
+		Content:        record.Content,
+		Interpretation: record.Interpretation,
+		KeyWords:       record.FormatKeyWords(),
+	}
+}
+
+// SubmitQuizAnswers 在服务端判分，总题数为练习的全部本地题目，model=true 时加上模型出的题目，
+// 没有作答的题目算错。登录用户的成绩会保存，匿名用户只返回判分结果
+func (h *Handler) SubmitQuizAnswers(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+	var req models.QuizAnswerRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	saved, err := database.GetQuiz(ctx, id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "判分失败")
+		return
+	}
+	var record *models.LearningRecord
+	if saved != nil {
+		if record, err = database.GetVisibleRecord(ctx, saved.RecordID); err != nil {
+			log.Printf("%v", err)
+			serverError(c, "判分失败")
+			return
+		}
+	}
+	if record == nil {
+		notFound(c, "练习不存在")
+		return
+	}
+
+	local, err := decodeQuestions(saved.Questions)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "判分失败")
+		return
+	}
+	model, err := decodeQuestions(saved.ModelQuestions)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "判分失败")
+		return
+	}
+	withModel := c.Query("model") == "true"
+	questions := local
+	if withModel {
+		questions = append(questions, model...)
+	}
+	known := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		known[q.ID] = true
+	}
+	modelIDs := make(map[string]bool, len(model))
+	for _, q := range model {
+		modelIDs[q.ID] = true
+	}
+
+	var errs []string
+	given := make(map[string]string, len(req.Answers))
+	for _, a := range req.Answers {
+		if !known[a.QuestionID] && modelIDs[a.QuestionID] {
+			errs = append(errs, fmt.Sprintf("题目 %s 是模型出的题目，提交时需带上 model=true", a.QuestionID))
+		} else if !known[a.QuestionID] {
+			errs = append(errs, fmt.Sprintf("题目 %s 不存在", a.QuestionID))
+		} else if _, ok := given[a.QuestionID]; ok {
+			errs = append(errs, fmt.Sprintf("题目 %s 重复提交", a.QuestionID))
+		}
+		given[a.QuestionID] = a.Answer
+	}
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	result := models.QuizResult{QuizID: saved.ID, RecordID: record.ID, Type: record.Type, Total: len(questions)}
+	answers := make([]models.QuizAnswerResult, 0, len(questions))
+	for _, q := range questions {
+		answer := given[q.ID]
+		correct := quiz.Grade(q, answer)
+		if correct {
+			result.Correct++
+		}
+		answers = append(answers, models.QuizAnswerResult{
+			QuestionID:    q.ID,
+			Answer:        answer,
+			Correct:       correct,
+			CorrectAnswer: q.Answer,
+			Explanation:   q.Explanation,
+		})
+	}
+
+	message := "判分完成"
+	if userID, ok := middleware.UserID(c); ok {
+		encoded, err := json.Marshal(answers)
+		if err != nil {
+			log.Printf("编码判分结果失败: %v", err)
+			serverError(c, "保存练习成绩失败")
+			return
+		}
+		result.UserID = userID
+		result.Answers = string(encoded)
+		if err := database.SaveQuizResult(ctx, &result); err != nil {
+			log.Printf("%v", err)
+			serverError(c, "保存练习成绩失败")
+			return
+		}
+		message = "判分完成，成绩已保存"
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    newQuizResultData(&result, answers),
+	})
+}
+
+// ListMyQuizResults 按提交时间倒序返回当前用户的练习成绩，可按 type 筛选
+func (h *Handler) ListMyQuizResults(c *gin.Context) {
+	userID, _ := middleware.UserID(c)
+	results, total, err := database.ListQuizResults(c.Request.Context(), userID, strings.ToLower(c.Query("type")),
+		queryInt(c, "limit", 20, 1, 200), queryInt(c, "offset", 0, 0, 1<<30))
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取练习成绩失败")
+		return
+	}
+
+	data := models.QuizResultListData{Results: make([]models.QuizResultData, 0, len(results)), Total: total}
+	for i := range results {
+		var answers []models.QuizAnswerResult
+		if results[i].Answers != "" {
+			if err := json.Unmarshal([]byte(results[i].Answers), &answers); err != nil {
+				log.Printf("解析练习成绩 %d 失败: %v", results[i].ID, err)
+			}
+		}
+		data.Results = append(data.Results, newQuizResultData(&results[i], answers))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取练习成绩成功",
+		Data:    data,
+	})
+}
+
+func newQuizResultData(result *models.QuizResult, answers []models.QuizAnswerResult) models.QuizResultData {
+	data := models.QuizResultData{
+		ID:       result.ID,
+		QuizID:   result.QuizID,
+		RecordID: result.RecordID,
+		Type:     result.Type,
+		TypeName: models.GetLearningTypeName(result.Type),
+		Correct:  result.Correct,
+		Total:    result.Total,
+		Saved:    result.ID != 0,
+		Answers:  answers,
+	}
+	if result.Total > 0 {
+		data.Score = result.Correct * 100 / result.Total
+	}
+	if !result.CreatedAt.IsZero() {
+		data.CreatedAt = result.CreatedAt.Format("2006-01-02 15:04:05")
+	}
+	return data
+}
@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListRecords 分页查看学习记录，包括出处存疑、旧版本和固定到未来日期的记录；
+// deleted=true 时包含已删除的记录
+func (h *Handler) AdminListRecords(c *gin.Context) {
+	limit := queryInt(c, "limit", 20, 1, 200)
+	offset := queryInt(c, "offset", 0, 0, 1<<30)
+	withDeleted := c.Query("deleted") == "true"
+
+	records, total, err := database.ListRecords(c.Request.Context(), strings.ToLower(c.Query("type")), withDeleted, limit, offset)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习记录成功",
+		Data:    models.AdminListData{Total: total, Items: records},
+	})
+}
+
+func (h *Handler) AdminGetRecord(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习记录成功",
+		Data:    record,
+	})
+}
+
+func (h *Handler) AdminCreateRecord(c *gin.Context) {
+	var req models.AdminRecordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	record := models.LearningRecord{
+		Verification: models.VerificationUnverified,
+		Date:         time.Now(),
+	}
+	if errs := applyRecordRequest(&record, req, true); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	if err := database.CreateRecord(c.Request.Context(), adminChange(c), &record); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "创建学习记录失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "创建学习记录成功",
+		Data:    record,
+	})
+}
+
+func (h *Handler) AdminUpdateRecord(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminRecordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	before := *record
+	if errs := applyRecordRequest(record, req, false); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	if err := database.UpdateRecord(c.Request.Context(), adminChange(c), before, record); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "更新学习记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "更新学习记录成功",
+		Data:    record,
+	})
+}
+
+func (h *Handler) AdminDeleteRecord(c *gin.Context) {
+	record, ok := h.loadRecord(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DeleteRecord(c.Request.Context(), adminChange(c), record); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "删除学习记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除学习记录成功",
+		Data:    gin.H{"id": record.ID},
+	})
+}
+
+// AdminRegenerate 重新生成指定类型的今日内容，替换当前内容；今日内容已固定时需先取消固定
+func (h *Handler) AdminRegenerate(c *gin.Context) {
+	learningType := strings.ToLower(c.Param("type"))
+	if !models.IsValidLearningType(learningType) {
+		validationError(c, []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))})
+		return
+	}
+
+	ctx := c.Request.Context()
+	pinned, err := database.GetPinnedRecord(ctx, learningType, time.Now())
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取固定内容失败")
+		return
+	}
+	if pinned != nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Message:   "今日内容已固定，请先取消固定再重新生成",
+			ErrorCode: "PINNED",
+			Data:      pinned,
+		})
+		return
+	}
+
+	before, err := database.GetTodayLearningRecord(ctx, learningType)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取今日学习记录失败")
+		return
+	}
+
+	record, err := h.generator.Regenerate(ctx, learningType)
+	if err != nil {
+		log.Printf("重新生成 %s 内容失败: %v", learningType, err)
+		serverError(c, "重新生成内容失败")
+		return
+	}
+
+	var beforeSnapshot interface{}
+	if before != nil {
+		beforeSnapshot = before
+	}
+	if err := database.SaveAuditEvent(ctx, adminChange(c), models.AuditRegenerate, models.EntityLearningRecord, record.ID, beforeSnapshot, record); err != nil {
+		log.Printf("%v", err)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "重新生成内容成功",
+		Data:    record,
+	})
+}
+
+// AdminPinRecord 把已有记录或新内容固定到指定日期，当天将展示该内容且不会被自动生成替换
+func (h *Handler) AdminPinRecord(c *gin.Context) {
+	var req models.PinRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+	if err != nil {
+		validationError(c, []string{"日期格式应为 2006-01-02"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var record *models.LearningRecord
+	if req.RecordID != nil {
+		record, err = database.GetRecord(ctx, *req.RecordID)
+		if err != nil {
+			log.Printf("%v", err)
+			serverError(c, "获取学习记录失败")
+			return
+		}
+		if record == nil {
+			notFound(c, "学习记录不存在")
+			return
+		}
+	} else {
+		record = &models.LearningRecord{Verification: models.VerificationUnverified}
+		errs := applyRecordRequest(record, models.AdminRecordRequest{
+			Type:           &req.Type,
+			Content:        &req.Content,
+			Interpretation: &req.Interpretation,
+			KeyWords:       req.KeyWords,
+		}, true)
+		if len(errs) > 0 {
+			validationError(c, errs)
+			return
+		}
+	}
+	if record.Verification == models.VerificationDisputed {
+		validationError(c, []string{"出处存疑的记录不能固定"})
+		return
+	}
+
+	record.Date = day
+	if err := database.PinRecord(ctx, adminChange(c), record); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "固定学习记录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "固定学习记录成功",
+		Data:    record,
+	})
+}
+
+func (h *Handler) AdminListLearned(c *gin.Context) {
+	limit := queryInt(c, "limit", 50, 1, 500)
+	offset := queryInt(c, "offset", 0, 0, 1<<30)
+
+	contents, total, err := database.ListLearned(c.Request.Context(), strings.ToLower(c.Query("type")), limit, offset)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取已学习内容失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取已学习内容成功",
+		Data:    models.AdminListData{Total: total, Items: contents},
+	})
+}
+
+func (h *Handler) AdminGetLearned(c *gin.Context) {
+	content, ok := h.loadLearned(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取已学习内容成功",
+		Data:    content,
+	})
+}
+
+func (h *Handler) AdminCreateLearned(c *gin.Context) {
+	var req models.AdminLearnedRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	content := models.LearnedContent{}
+	if errs := applyLearnedRequest(&content, req); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	if err := database.CreateLearned(c.Request.Context(), adminChange(c), &content); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "创建已学习内容失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "创建已学习内容成功",
+		Data:    content,
+	})
+}
+
+func (h *Handler) AdminUpdateLearned(c *gin.Context) {
+	content, ok := h.loadLearned(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminLearnedRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	before := *content
+	if errs := applyLearnedRequest(content, req); len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	if err := database.UpdateLearned(c.Request.Context(), adminChange(c), before, content); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "更新已学习内容失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "更新已学习内容成功",
+		Data:    content,
+	})
+}
+
+func (h *Handler) AdminDeleteLearned(c *gin.Context) {
+	content, ok := h.loadLearned(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DeleteLearned(c.Request.Context(), adminChange(c), content); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "删除已学习内容失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "删除已学习内容成功",
+		Data:    gin.H{"id": content.ID},
+	})
+}
+
+func (h *Handler) loadRecord(c *gin.Context) (*models.LearningRecord, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+
+	record, err := database.GetRecord(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习记录失败")
+		return nil, false
+	}
+	if record == nil {
+		notFound(c, "学习记录不存在")
+		return nil, false
+	}
+	return record, true
+}
+
+func (h *Handler) loadLearned(c *gin.Context) (*models.LearnedContent, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return nil, false
+	}
+
+	content, err := database.GetLearned(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取已学习内容失败")
+		return nil, false
+	}
+	if content == nil {
+		notFound(c, "已学习内容不存在")
+		return nil, false
+	}
+	return content, true
+}
+
+// applyRecordRequest 把请求中的字段写入记录并校验，create 为 true 时类型、内容、释义和关键词必填
+func applyRecordRequest(record *models.LearningRecord, req models.AdminRecordRequest, create bool) []string {
+	if req.Type != nil {
+		record.Type = strings.ToLower(strings.TrimSpace(*req.Type))
+	}
+	if req.Content != nil {
+		record.Content = strings.TrimSpace(*req.Content)
+	}
+	if req.Interpretation != nil {
+		record.Interpretation = strings.TrimSpace(*req.Interpretation)
+	}
+	if req.KeyWords != nil || create {
+		record.KeyWords = (&models.LearningContent{KeyWords: req.KeyWords}).FormatKeyWords()
+	}
+	if req.SourceID != nil {
+		record.SourceID = *req.SourceID
+	}
+	if req.Verification != nil {
+		record.Verification = *req.Verification
+	}
+	if req.VerificationNote != nil {
+		record.VerificationNote = *req.VerificationNote
+	}
+	var errs []string
+	if req.Pinned != nil {
+		if *req.Pinned {
+			errs = append(errs, "固定内容请使用 POST /admin/pins")
+		}
+		record.Pinned = false
+	}
+	if req.Date != nil {
+		day, err := time.ParseInLocation("2006-01-02", *req.Date, time.Local)
+		if err != nil {
+			errs = append(errs, "日期格式应为 2006-01-02")
+		} else {
+			record.Date = day
+		}
+	}
+
+	if !models.IsValidLearningType(record.Type) {
+		errs = append(errs, fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", ")))
+	}
+	switch record.Verification {
+	case models.VerificationVerified, models.VerificationUnverified, models.VerificationDisputed:
+	default:
+		errs = append(errs, "verification 只能是 verified、unverified 或 disputed")
+	}
+
+	content := models.LearningContent{
+		Content:        record.Content,
+		Interpretation: record.Interpretation,
+		KeyWords:       record.FormatKeyWords(),
+	}
+	return append(errs, content.Validate()...)
+}
+
+func applyLearnedRequest(content *models.LearnedContent, req models.AdminLearnedRequest) []string {
+	content.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	content.Content = strings.TrimSpace(req.Content)
+
+	var errs []string
+	if !models.IsValidLearningType(content.Type) {
+		errs = append(errs, fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", ")))
+	}
+	if content.Content == "" {
+		errs = append(errs, "内容不能为空")
+	}
+	return errs
+}
+
+// adminChange 返回当前管理员和请求头 X-Audit-Reason 中的变更原因，写入审计日志
+func adminChange(c *gin.Context) database.Change {
+	return database.Change{
+		Actor:  middleware.AdminActor(c),
+		Reason: strings.TrimSpace(c.GetHeader("X-Audit-Reason")),
+	}
+}
+
+func paramID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的记录ID",
+			ErrorCode: "INVALID_ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "请求参数错误",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{err.Error()},
+		})
+		return false
+	}
+	return true
+}
+
+func validationError(c *gin.Context, errs []string) {
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success:   false,
+		Message:   "数据验证失败",
+		ErrorCode: "VALIDATION_ERROR",
+		Errors:    errs,
+	})
+}
+
+func notFound(c *gin.Context, message string) {
+	c.JSON(http.StatusNotFound, models.APIResponse{
+		Success:   false,
+		Message:   message,
+		ErrorCode: "NOT_FOUND",
+	})
+}
+
+func serverError(c *gin.Context, message string) {
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success:   false,
+		Message:   message,
+		ErrorCode: "SERVER_ERROR",
+	})
+}
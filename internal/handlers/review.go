@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"everyday-study-backend/internal/review"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReviewQueue 返回当前用户今天需要复习的内容，可按 type 筛选，limit 限制返回条数
+func (h *Handler) GetReviewQueue(c *gin.Context) {
+	userID, _ := middleware.UserID(c)
+	learningType := strings.ToLower(c.Query("type"))
+	now := time.Now()
+
+	states, records, err := database.ListDueReviews(c.Request.Context(), userID, learningType, now, queryInt(c, "limit", 20, 1, 200))
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取复习队列失败")
+		return
+	}
+	counts, err := database.CountDueReviews(c.Request.Context(), userID, now)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取复习队列失败")
+		return
+	}
+
+	data := models.ReviewQueueData{Items: make([]models.ReviewItem, 0, len(states))}
+	for t, n := range counts {
+		if learningType == "" || t == learningType {
+			data.Due += n
+		}
+	}
+	for i := range states {
+		record, ok := records[states[i].RecordID]
+		if !ok {
+			continue
+		}
+		data.Items = append(data.Items, newReviewItem(&states[i], &record))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取复习队列成功",
+		Data:    data,
+	})
+}
+
+// SubmitReview 提交对某条学习记录的回忆评分，返回更新后的复习进度和下次复习日期
+func (h *Handler) SubmitReview(c *gin.Context) {
+	record, ok := h.loadVisibleRecord(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if *req.Grade < review.MinGrade || *req.Grade > review.MaxGrade {
+		validationError(c, []string{fmt.Sprintf("grade 应在 %d 到 %d 之间", review.MinGrade, review.MaxGrade)})
+		return
+	}
+
+	userID, _ := middleware.UserID(c)
+	state, err := database.SubmitReview(c.Request.Context(), userID, record, *req.Grade, time.Now())
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "保存复习进度失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("已记录，%d 天后再复习", state.Interval),
+		Data:    newReviewItem(state, record),
+	})
+}
+
+func newReviewItem(state *models.ReviewState, record *models.LearningRecord) models.ReviewItem {
+	item := models.ReviewItem{
+		Record:       newLearningHistoryItem(record),
+		New:          state.ID == 0,
+		IntervalDays: state.Interval,
+		Repetitions:  state.Repetitions,
+		Ease:         state.Ease,
+		Lapses:       state.Lapses,
+		Reviews:      state.Reviews,
+	}
+	if !item.New {
+		item.DueAt = &state.DueAt
+	}
+	return item
+}
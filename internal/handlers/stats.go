@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStatsCalendar 返回一年的学习日历和连续天数、每月数量、关键词/作者/出处分布，
+// 可按 type 筛选；登录用户额外返回自己的日历
+func (h *Handler) GetStatsCalendar(c *gin.Context) {
+	learningType := strings.ToLower(c.Query("type"))
+	if learningType != "" && !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return
+	}
+	year := queryInt(c, "year", time.Now().Year(), 2000, 9999)
+	top := queryInt(c, "top", 20, 1, 100)
+
+	global, err := database.GetCalendarStats(c.Request.Context(), 0, learningType, year, top)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取学习日历失败")
+		return
+	}
+	data := models.StatsCalendarData{Year: year, Type: learningType, Global: *global}
+	if userID, ok := middleware.UserID(c); ok {
+		data.User, err = database.GetCalendarStats(c.Request.Context(), userID, learningType, year, top)
+		if err != nil {
+			log.Printf("%v", err)
+			serverError(c, "获取学习日历失败")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取学习日历成功",
+		Data:    data,
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/generator"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSE 连接上定期发送的注释行，避免代理在模型长时间没有输出时断开空闲连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamTodayLearning 通过 SSE 推送今日内容的生成进度。
+// 事件依次为 status（生成阶段）、partial（模型输出片段）、final（保存后的记录）或 error；
+// 客户端处理不及时会收到 snapshot（完整的已生成文本，替换已显示的内容）。
+// 今日内容已缓存时只推送一条 final。
+func (h *Handler) StreamTodayLearning(c *gin.Context) {
+	learningType := c.Param("type")
+
+	if !models.IsValidLearningType(learningType) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "无效的学习类型",
+			ErrorCode: "VALIDATION_ERROR",
+			Errors:    []string{fmt.Sprintf("支持的类型: %s", strings.Join(models.GetAllLearningTypes(), ", "))},
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	todayRecord, err := database.GetTodayLearningRecord(c.Request.Context(), learningType)
+	if err != nil {
+		log.Printf("获取今日学习记录失败: %v", err)
+		c.SSEvent("error", gin.H{"message": "获取今日学习记录失败"})
+		return
+	}
+
+	if todayRecord != nil {
+		recordView(c, todayRecord)
+		c.SSEvent("final", newTodayLearningData(todayRecord, true))
+		return
+	}
+
+	sub := h.generator.Subscribe(learningType)
+	defer sub.Close()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev := <-sub.Events:
+			sendGenerationEvent(c, ev)
+			return true
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		case <-sub.Done:
+			for {
+				select {
+				case ev := <-sub.Events:
+					sendGenerationEvent(c, ev)
+					continue
+				default:
+				}
+				break
+			}
+
+			record, err := sub.Result()
+			if err != nil {
+				c.SSEvent("error", gin.H{"message": fmt.Sprintf("生成学习内容失败: %s", err.Error())})
+				return false
+			}
+			recordView(c, record)
+			c.SSEvent("final", newTodayLearningData(record, false))
+			return false
+		case <-ctx.Done():
+			log.Printf("客户端已断开，%s内容将在后台继续生成", models.GetLearningTypeName(learningType))
+			return false
+		}
+	})
+}
+
+func sendGenerationEvent(c *gin.Context, ev generator.Event) {
+	switch ev.Kind {
+	case generator.EventStatus:
+		c.SSEvent("status", gin.H{"status": ev.Data})
+	case generator.EventDelta:
+		c.SSEvent("partial", gin.H{"text": ev.Data})
+	case generator.EventSnapshot:
+		c.SSEvent("snapshot", gin.H{"text": ev.Data})
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"everyday-study-backend/internal/auth"
+	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/middleware"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+const (
+	minPasswordLength = 8
+	// bcrypt 只使用密码的前 72 个字节
+	maxPasswordBytes = 72
+)
+
+func (h *Handler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	username := strings.ToLower(strings.TrimSpace(req.Username))
+	var errs []string
+	if !usernamePattern.MatchString(username) {
+		errs = append(errs, "用户名为 3 到 32 个字符，只能包含字母、数字、下划线、点和减号")
+	}
+	if utf8.RuneCountInString(req.Password) < minPasswordLength || len(req.Password) > maxPasswordBytes {
+		errs = append(errs, fmt.Sprintf("密码长度应为 %d 到 %d 个字节", minPasswordLength, maxPasswordBytes))
+	}
+	if len(errs) > 0 {
+		validationError(c, errs)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("密码加密失败: %v", err)
+		serverError(c, "注册失败")
+		return
+	}
+
+	user := models.User{
+		Username:     username,
+		PasswordHash: hash,
+		DisplayName:  strings.TrimSpace(req.DisplayName),
+	}
+	if err := database.CreateUser(c.Request.Context(), &user); err != nil {
+		if err == database.ErrUsernameTaken {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success:   false,
+				Message:   err.Error(),
+				ErrorCode: "CONFLICT",
+			})
+			return
+		}
+		log.Printf("%v", err)
+		serverError(c, "注册失败")
+		return
+	}
+
+	log.Printf("👤 新用户注册: %s（ID: %d）", user.Username, user.ID)
+	h.respondTokens(c, http.StatusCreated, "注册成功", &user)
+}
+
+func (h *Handler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := database.GetUserByUsername(c.Request.Context(), strings.ToLower(strings.TrimSpace(req.Username)))
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "登录失败")
+		return
+	}
+
+	// 用户不存在时与固定的哈希比较，耗时与密码错误相同，结果一律视为失败
+	hash := auth.DummyPasswordHash
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !auth.CheckPassword(hash, req.Password) || user == nil {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success:   false,
+			Message:   "用户名或密码错误",
+			ErrorCode: "UNAUTHORIZED",
+		})
+		return
+	}
+
+	if err := database.TouchLogin(c.Request.Context(), user); err != nil {
+		log.Printf("%v", err)
+	}
+	h.respondTokens(c, http.StatusOK, "登录成功", user)
+}
+
+// RefreshToken 用 refresh token 换取新的 access token 和 refresh token，旧的 refresh token 随即失效
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req models.RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	claims, err := h.tokens.Parse(req.RefreshToken, auth.KindRefresh)
+	if err != nil {
+		invalidRefreshToken(c)
+		return
+	}
+	user, err := database.GetUserByID(c.Request.Context(), claims.UserID())
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "刷新令牌失败")
+		return
+	}
+	if user == nil {
+		invalidRefreshToken(c)
+		return
+	}
+
+	refresh, err := h.tokens.IssueRefresh(user.ID, user.Username)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "刷新令牌失败")
+		return
+	}
+	if err := database.RotateRefreshToken(c.Request.Context(), user.ID, claims.ID, refresh.ID, refresh.ExpiresAt); err != nil {
+		if err == database.ErrInvalidRefreshToken {
+			invalidRefreshToken(c)
+			return
+		}
+		log.Printf("%v", err)
+		serverError(c, "刷新令牌失败")
+		return
+	}
+
+	access, err := h.tokens.IssueAccess(user.ID, user.Username)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "刷新令牌失败")
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "刷新令牌成功",
+		Data:    newAuthData(access, refresh, user),
+	})
+}
+
+// Logout 吊销请求中的 refresh token；access token 在过期前仍然有效
+func (h *Handler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	claims, err := h.tokens.Parse(req.RefreshToken, auth.KindRefresh)
+	if err != nil {
+		invalidRefreshToken(c)
+		return
+	}
+	if err := database.RevokeRefreshToken(c.Request.Context(), claims.UserID(), claims.ID); err != nil {
+		log.Printf("%v", err)
+		serverError(c, "退出登录失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "已退出登录",
+	})
+}
+
+func (h *Handler) GetMe(c *gin.Context) {
+	userID, _ := middleware.UserID(c)
+	user, err := database.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "获取用户信息失败")
+		return
+	}
+	if user == nil {
+		notFound(c, "用户不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "获取用户信息成功",
+		Data:    user,
+	})
+}
+
+// respondTokens 为用户签发一对新令牌并返回
+func (h *Handler) respondTokens(c *gin.Context, status int, message string, user *models.User) {
+	access, err := h.tokens.IssueAccess(user.ID, user.Username)
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "签发令牌失败")
+		return
+	}
+	refresh, err := h.tokens.IssueRefresh(user.ID, user.Username)
+	if err == nil {
+		err = database.SaveRefreshToken(c.Request.Context(), user.ID, refresh.ID, refresh.ExpiresAt)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		serverError(c, "签发令牌失败")
+		return
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    newAuthData(access, refresh, user),
+	})
+}
+
+func newAuthData(access, refresh *auth.Token, user *models.User) models.AuthData {
+	return models.AuthData{
+		AccessToken:      access.Value,
+		RefreshToken:     refresh.Value,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(time.Until(access.ExpiresAt).Seconds()),
+		RefreshExpiresIn: int64(time.Until(refresh.ExpiresAt).Seconds()),
+		User:             user,
+	}
+}
+
+func invalidRefreshToken(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, models.APIResponse{
+		Success:   false,
+		Message:   database.ErrInvalidRefreshToken.Error(),
+		ErrorCode: "INVALID_TOKEN",
+	})
+}
+
+// learningHistory 登录用户返回自己看过的内容，匿名访问返回全站的历史内容
+func (h *Handler) learningHistory(c *gin.Context, learningType string, limit int) ([]models.LearningRecord, error) {
+	if userID, ok := middleware.UserID(c); ok {
+		return database.GetUserHistory(c.Request.Context(), userID, learningType, limit)
+	}
+	return database.GetLearningHistory(c.Request.Context(), learningType, limit)
+}
+
+// recordView 把登录用户看到的内容记入其学习历史，匿名访问时不记录
+func recordView(c *gin.Context, record *models.LearningRecord) {
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	if err := database.RecordView(c.Request.Context(), userID, record); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"everyday-study-backend/internal/config"
+	"everyday-study-backend/internal/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const adminActorKey = "admin_actor"
+
+// HMAC 签名中时间戳允许的最大偏差，防止请求被截获后重放
+const hmacMaxSkew = 5 * time.Minute
+
+// seenSignatures 记录有效期内已经使用过的签名，同一签名只能使用一次。
+// 只在当前进程内生效，多实例部署时重放仍可能落到其他实例上
+type seenSignatures struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// use 登记签名，签名已经用过时返回 false；顺带清理过期的签名
+func (s *seenSignatures) use(signature string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for sig, exp := range s.expires {
+		if now.After(exp) {
+			delete(s.expires, sig)
+		}
+	}
+	if _, ok := s.expires[signature]; ok {
+		return false
+	}
+	s.expires[signature] = expires
+	return true
+}
+
+// AdminAuth 校验管理员身份，支持两种方式：
+//   - Authorization: Bearer <token>，token 需在 ADMIN_TOKENS 中
+//   - X-Admin-Key、X-Admin-Timestamp、X-Admin-Signature 三个请求头，签名为
+//     hex(HMAC-SHA256(secret, METHOD\nURI\nTIMESTAMP\nhex(SHA256(body))))，密钥来自 ADMIN_HMAC_KEYS
+//
+// 签名在时间戳有效期内只能使用一次，同一秒内需要重复相同请求时需等到下一秒重新签名。
+// 通过后凭据名称记为操作人，可用 AdminActor 读取
+func AdminAuth(tokens []config.AdminKey, hmacKeys []config.AdminKey) gin.HandlerFunc {
+	seen := &seenSignatures{expires: make(map[string]time.Time)}
+
+	return func(c *gin.Context) {
+		var actor string
+		if c.GetHeader("X-Admin-Signature") != "" {
+			actor = matchSignature(c, hmacKeys, seen)
+		} else {
+			token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+			if token != "" {
+				actor = matchToken(token, tokens)
+			}
+		}
+
+		if actor == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.APIResponse{
+				Success:   false,
+				Message:   "未授权的访问",
+				ErrorCode: "UNAUTHORIZED",
+			})
+			return
+		}
+		c.Set(adminActorKey, actor)
+		c.Next()
+	}
+}
+
+// AdminActor 返回通过认证的管理员名称
+func AdminActor(c *gin.Context) string {
+	return c.GetString(adminActorKey)
+}
+
+func matchToken(token string, tokens []config.AdminKey) string {
+	actor := ""
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.Secret)) == 1 {
+			actor = t.Name
+		}
+	}
+	return actor
+}
+
+func matchSignature(c *gin.Context, keys []config.AdminKey, seen *seenSignatures) string {
+	name := c.GetHeader("X-Admin-Key")
+	timestamp := c.GetHeader("X-Admin-Timestamp")
+	signature, err := hex.DecodeString(c.GetHeader("X-Admin-Signature"))
+	if name == "" || err != nil {
+		return ""
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ""
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return ""
+	}
+
+	var secret string
+	for _, k := range keys {
+		if k.Name == name {
+			secret = k.Secret
+		}
+	}
+	if secret == "" {
+		return ""
+	}
+
+	// 读取请求体计算摘要后放回，供后续处理函数使用
+	var body []byte
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(c.Request.Method + "\n" + c.Request.URL.RequestURI() + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return ""
+	}
+	if !seen.use(hex.EncodeToString(signature), time.Unix(ts, 0).Add(hmacMaxSkew)) {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"everyday-study-backend/internal/auth"
+	"everyday-study-backend/internal/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userIDKey       = "user_id"
+	usernameKey     = "username"
+	invalidTokenKey = "invalid_token"
+)
+
+// OptionalUser 解析 Authorization: Bearer <access token>。没有携带令牌或令牌无效、过期时都按匿名用户继续处理，
+// 公开接口不受影响；令牌无效会记录下来，由 RequireUser 返回 401 提示客户端刷新令牌
+func OptionalUser(tokens *auth.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		value := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		claims, err := tokens.Parse(value, auth.KindAccess)
+		if err != nil {
+			c.Set(invalidTokenKey, true)
+			c.Next()
+			return
+		}
+
+		c.Set(userIDKey, claims.UserID())
+		c.Set(usernameKey, claims.Username)
+		c.Next()
+	}
+}
+
+// RequireUser 要求请求已登录，需放在 OptionalUser 之后
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := UserID(c); !ok {
+			if c.GetBool(invalidTokenKey) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, models.APIResponse{
+					Success:   false,
+					Message:   "登录已失效，请刷新令牌或重新登录",
+					ErrorCode: "INVALID_TOKEN",
+				})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.APIResponse{
+				Success:   false,
+				Message:   "请先登录",
+				ErrorCode: "UNAUTHORIZED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserID 返回当前登录用户的ID，匿名访问时 ok 为 false
+func UserID(c *gin.Context) (uint, bool) {
+	id := c.GetUint(userIDKey)
+	return id, id != 0
+}
@@ -0,0 +1,188 @@
+package mockllm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 内置的默认录制，未指定录制目录时使用，保证离线也能跑通全部流程
+//
+//go:embed cassettes/*.json
+var defaultFS embed.FS
+
+// Interaction 是一条录制的模型响应。
+// Key 为请求指纹，回放时优先精确匹配；匹配不到时按学习类型和用途轮流返回，
+// Type 为 * 时匹配任意类型。
+type Interaction struct {
+	Key              string          `json:"key,omitempty"`
+	Type             string          `json:"type"`
+	Purpose          string          `json:"purpose"`
+	Model            string          `json:"model,omitempty"`
+	Content          json.RawMessage `json:"content"`
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	Usage            *models.Usage   `json:"usage,omitempty"`
+	RecordedAt       string          `json:"recorded_at,omitempty"`
+}
+
+// Text 返回模型输出文本。手写录制时 content 可以直接写成 JSON 对象
+func (it *Interaction) Text() string {
+	var s string
+	if err := json.Unmarshal(it.Content, &s); err == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, it.Content); err != nil {
+		return string(it.Content)
+	}
+	return buf.String()
+}
+
+// Cassette 是一组录制响应，可以从目录加载，录制模式下新的响应追加到 recorded.json
+type Cassette struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	next         map[string]int
+	recordPath   string
+}
+
+// LoadCassette 加载目录下的全部 JSON 录制文件，dir 为空时使用内置录制
+func LoadCassette(dir string) (*Cassette, error) {
+	c := &Cassette{next: make(map[string]int)}
+
+	if dir == "" {
+		entries, err := defaultFS.ReadDir("cassettes")
+		if err != nil {
+			return nil, fmt.Errorf("读取内置录制失败: %v", err)
+		}
+		for _, entry := range entries {
+			data, err := defaultFS.ReadFile("cassettes/" + entry.Name())
+			if err != nil {
+				return nil, fmt.Errorf("读取内置录制失败: %v", err)
+			}
+			if err := c.add(entry.Name(), data); err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取录制目录失败: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取录制文件失败: %v", err)
+		}
+		if err := c.add(filepath.Base(file), data); err != nil {
+			return nil, err
+		}
+	}
+	c.recordPath = filepath.Join(dir, "recorded.json")
+	return c, nil
+}
+
+func (c *Cassette) add(name string, data []byte) error {
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return fmt.Errorf("解析录制文件 %s 失败: %v", name, err)
+	}
+	for i := range interactions {
+		if interactions[i].Purpose == "" || len(interactions[i].Content) == 0 {
+			return fmt.Errorf("录制文件 %s 第 %d 条缺少 purpose 或 content", name, i+1)
+		}
+		if interactions[i].Type == "" {
+			interactions[i].Type = "*"
+		}
+	}
+	c.interactions = append(c.interactions, interactions...)
+	return nil
+}
+
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+// Match 查找请求对应的录制响应：先按指纹精确匹配，再按类型和用途轮流返回
+func (c *Cassette) Match(key string, learningType string, purpose string) (*Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.interactions {
+		if key != "" && c.interactions[i].Key == key {
+			return &c.interactions[i], true
+		}
+	}
+
+	for _, t := range []string{learningType, "*"} {
+		var candidates []int
+		for i, it := range c.interactions {
+			if it.Type == t && it.Purpose == purpose {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		label := t + "/" + purpose
+		n := c.next[label]
+		c.next[label] = n + 1
+		return &c.interactions[candidates[n%len(candidates)]], true
+	}
+	return nil, false
+}
+
+// Record 保存一条新录制，并写回 recorded.json
+func (c *Cassette) Record(it Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, it)
+	if c.recordPath == "" {
+		return nil
+	}
+
+	var recorded []Interaction
+	if data, err := os.ReadFile(c.recordPath); err == nil {
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			return fmt.Errorf("解析录制文件失败: %v", err)
+		}
+	}
+	recorded = append(recorded, it)
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化录制失败: %v", err)
+	}
+	if err := os.WriteFile(c.recordPath, data, 0o644); err != nil {
+		return fmt.Errorf("写入录制文件失败: %v", err)
+	}
+	return nil
+}
+
+// RequestKey 计算请求指纹：模型名和全部消息内容相同的请求视为同一请求
+func RequestKey(req *models.VolcanoAPIRequest) string {
+	h := sha256.New()
+	h.Write([]byte(req.Model))
+	for _, msg := range req.Messages {
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(strings.TrimSpace(msg.Content)))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
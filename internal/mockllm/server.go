@@ -0,0 +1,350 @@
+package mockllm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"everyday-study-backend/internal/models"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 请求中标注学习类型和调用用途的请求头，模拟服务据此匹配录制；真实接口会忽略它们
+const (
+	HeaderLearningType = "X-Learning-Type"
+	HeaderPurpose      = "X-Call-Purpose"
+)
+
+// 故障注入类型，另外任意三位数字表示返回该 HTTP 状态码
+const (
+	FailureTimeout   = "timeout"
+	FailureMalformed = "malformed"
+	FailureEmpty     = "empty"
+)
+
+// Failure 是一种按比例注入的故障
+type Failure struct {
+	Kind string
+	Rate float64
+}
+
+// ParseFailures 解析故障注入配置，例如 "500:0.2,timeout:0.1,malformed"，比例缺省为 1
+func ParseFailures(spec string) ([]Failure, error) {
+	var failures []Failure
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kind, rateText, hasRate := strings.Cut(item, ":")
+		failure := Failure{Kind: strings.ToLower(strings.TrimSpace(kind)), Rate: 1}
+		if hasRate {
+			rate, err := strconv.ParseFloat(strings.TrimSpace(rateText), 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("故障比例无效: %s", item)
+			}
+			failure.Rate = rate
+		}
+
+		switch failure.Kind {
+		case FailureTimeout, FailureMalformed, FailureEmpty:
+		default:
+			if code, err := strconv.Atoi(failure.Kind); err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("未知的故障类型: %s", failure.Kind)
+			}
+		}
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+type Options struct {
+	// 录制文件目录，为空时使用内置录制
+	CassetteDir string
+	Failures    []Failure
+	// 每次响应前的固定延迟
+	Latency time.Duration
+	// 录制模式：请求转发到真实接口，响应保存到 CassetteDir/recorded.json
+	UpstreamURL string
+	UpstreamKey string
+}
+
+// Server 是兼容 /chat/completions 的模拟大模型服务，回放录制的响应，支持流式和故障注入
+type Server struct {
+	opts     Options
+	cassette *Cassette
+	client   *http.Client
+}
+
+func New(opts Options) (*Server, error) {
+	if opts.UpstreamURL != "" && opts.CassetteDir == "" {
+		return nil, fmt.Errorf("录制模式需要指定录制目录")
+	}
+
+	cassette, err := LoadCassette(opts.CassetteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		opts:     opts,
+		cassette: cassette,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (s *Server) Cassette() *Cassette {
+	return s.cassette
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeError(w, http.StatusNotFound, "路径不存在")
+		return
+	}
+
+	var req models.VolcanoAPIRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("解析请求失败: %v", err))
+		return
+	}
+
+	if s.opts.Latency > 0 {
+		select {
+		case <-time.After(s.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if kind := s.pickFailure(); kind != "" {
+		log.Printf("💥 模拟故障: %s", kind)
+		s.fail(w, r, &req, kind)
+		return
+	}
+
+	learningType := r.Header.Get(HeaderLearningType)
+	purpose := r.Header.Get(HeaderPurpose)
+	if purpose == "" {
+		purpose = models.PurposeGenerate
+	}
+	key := RequestKey(&req)
+
+	var it *Interaction
+	if s.opts.UpstreamURL != "" {
+		recorded, err := s.record(r.Context(), &req, key, learningType, purpose)
+		if err != nil {
+			log.Printf("❌ 录制失败: %v", err)
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		it = recorded
+	} else {
+		matched, ok := s.cassette.Match(key, learningType, purpose)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("没有匹配的录制响应: %s/%s", learningType, purpose))
+			return
+		}
+		it = matched
+	}
+
+	if req.Stream {
+		s.writeStream(w, &req, it)
+	} else {
+		s.writeJSON(w, &req, it)
+	}
+}
+
+func (s *Server) pickFailure() string {
+	for _, failure := range s.opts.Failures {
+		if rand.Float64() < failure.Rate {
+			return failure.Kind
+		}
+	}
+	return ""
+}
+
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, req *models.VolcanoAPIRequest, kind string) {
+	switch kind {
+	case FailureTimeout:
+		// 一直不响应，直到调用方超时或取消
+		<-r.Context().Done()
+	case FailureMalformed:
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: {\"choices\": [{\"delta\": \n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices": [{"message": {"role": "assistant", "content": `)
+	case FailureEmpty:
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices": []}`)
+	default:
+		code, _ := strconv.Atoi(kind)
+		writeError(w, code, "模拟故障")
+	}
+}
+
+// record 把请求转发到真实接口并保存响应。流式请求以非流式转发，回放时再切分成流
+func (s *Server) record(ctx context.Context, req *models.VolcanoAPIRequest, key string, learningType string, purpose string) (*Interaction, error) {
+	upstream := *req
+	upstream.Stream = false
+	upstream.StreamOptions = nil
+
+	body, err := json.Marshal(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.opts.UpstreamURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+s.opts.UpstreamKey)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求上游失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取上游响应失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("上游返回状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var apiResponse models.VolcanoAPIResponse
+	if err := json.Unmarshal(respBody, &apiResponse); err != nil {
+		return nil, fmt.Errorf("解析上游响应失败: %v", err)
+	}
+	if len(apiResponse.Choices) == 0 {
+		return nil, fmt.Errorf("上游返回空响应")
+	}
+
+	content, _ := json.Marshal(apiResponse.Choices[0].Message.Content)
+	usage := apiResponse.Usage
+	it := Interaction{
+		Key:              key,
+		Type:             learningType,
+		Purpose:          purpose,
+		Model:            apiResponse.Model,
+		Content:          content,
+		ReasoningContent: apiResponse.Choices[0].Message.ReasoningContent,
+		Usage:            &usage,
+		RecordedAt:       time.Now().Format(time.RFC3339),
+	}
+	if err := s.cassette.Record(it); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📼 已录制 %s/%s 响应: %s", learningType, purpose, key)
+	return &it, nil
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, req *models.VolcanoAPIRequest, it *Interaction) {
+	resp := models.VolcanoAPIResponse{
+		Model: responseModel(req, it),
+		Choices: []models.Choice{
+			{Message: models.Message{Role: "assistant", Content: it.Text(), ReasoningContent: it.ReasoningContent}},
+		},
+		Usage: usageFor(req, it),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeStream 把录制的文本切成小段，按 SSE 格式逐段推送，最后附带用量
+func (s *Server) writeStream(w http.ResponseWriter, req *models.VolcanoAPIRequest, it *Interaction) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	model := responseModel(req, it)
+	send := func(chunk models.VolcanoStreamChunk) {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for _, piece := range split(it.ReasoningContent, 16) {
+		send(models.VolcanoStreamChunk{Model: model, Choices: []models.StreamChoice{{Delta: models.Message{ReasoningContent: piece}}}})
+	}
+	for _, piece := range split(it.Text(), 8) {
+		send(models.VolcanoStreamChunk{Model: model, Choices: []models.StreamChoice{{Delta: models.Message{Content: piece}}}})
+	}
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		usage := usageFor(req, it)
+		send(models.VolcanoStreamChunk{Model: model, Choices: []models.StreamChoice{}, Usage: &usage})
+	}
+
+	io.WriteString(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func responseModel(req *models.VolcanoAPIRequest, it *Interaction) string {
+	if it.Model != "" {
+		return it.Model
+	}
+	return req.Model
+}
+
+// usageFor 返回录制的用量，没有录制时按字符数粗略估算
+func usageFor(req *models.VolcanoAPIRequest, it *Interaction) models.Usage {
+	if it.Usage != nil {
+		return *it.Usage
+	}
+
+	prompt := 0
+	for _, msg := range req.Messages {
+		prompt += utf8.RuneCountInString(msg.Content)
+	}
+	completion := utf8.RuneCountInString(it.Text()) + utf8.RuneCountInString(it.ReasoningContent)
+	return models.Usage{
+		PromptTokens:     prompt / 2,
+		CompletionTokens: completion / 2,
+		TotalTokens:      (prompt + completion) / 2,
+	}
+}
+
+func split(s string, size int) []string {
+	runes := []rune(s)
+	var pieces []string
+	for i := 0; i < len(runes); i += size {
+		end := i + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		pieces = append(pieces, string(runes[i:end]))
+	}
+	return pieces
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    "mock_error",
+		},
+	})
+}
@@ -0,0 +1,77 @@
+package mockllm
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport 把请求直接交给进程内的 handler 处理，不经过网络，供内置模拟模式使用。
+// 响应体通过管道传递，流式响应可以边写边读。
+type Transport struct {
+	Handler http.Handler
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	rw := &pipeResponseWriter{
+		header: make(http.Header),
+		pw:     pw,
+		ready:  make(chan struct{}),
+	}
+
+	go func() {
+		defer func() {
+			rw.WriteHeader(http.StatusOK)
+			pw.Close()
+		}()
+		t.Handler.ServeHTTP(rw, req)
+	}()
+
+	select {
+	case <-rw.ready:
+	case <-req.Context().Done():
+		pr.CloseWithError(req.Context().Err())
+		return nil, req.Context().Err()
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(rw.status),
+		StatusCode:    rw.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rw.sent,
+		Body:          pr,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+type pipeResponseWriter struct {
+	header http.Header
+	pw     *io.PipeWriter
+	status int
+	sent   http.Header
+	once   sync.Once
+	ready  chan struct{}
+}
+
+func (w *pipeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *pipeResponseWriter) WriteHeader(code int) {
+	w.once.Do(func() {
+		w.status = code
+		w.sent = w.header.Clone()
+		close(w.ready)
+	})
+}
+
+func (w *pipeResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.pw.Write(p)
+}
+
+func (w *pipeResponseWriter) Flush() {}
@@ -3,19 +3,51 @@ package models
 import (
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type LearningRecord struct {
-	ID             uint      `json:"id" gorm:"primaryKey"`
-	Type           string    `json:"type" gorm:"not null"`
-	Content        string    `json:"content" gorm:"type:text;not null"`
-	Interpretation string    `json:"interpretation" gorm:"type:text;not null"`
-	KeyWords       string    `json:"key_words" gorm:"type:text"`
-	Date           time.Time `json:"date" gorm:"type:date;not null"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	Type           string `json:"type" gorm:"not null"`
+	Content        string `json:"content" gorm:"type:text;not null"`
+	Interpretation string `json:"interpretation" gorm:"type:text;not null"`
+	KeyWords       string `json:"key_words" gorm:"type:text"`
+	SourceID       string `json:"source_id" gorm:"index"`
+	// 出处核验结果，disputed 的记录不会出现在公开接口中
+	Verification     string `json:"verification" gorm:"index;default:unverified"`
+	VerificationNote string `json:"verification_note" gorm:"type:text"`
+	// 产出该记录的模型调用，纯本地语料的记录为空
+	AttemptID *uint `json:"attempt_id" gorm:"index"`
+	// 产出该记录的回退链层级：模型名或 corpus
+	Tier string `json:"tier" gorm:"index"`
+	// 生成时使用的提示词实验版本，未参与实验时为空
+	Variant string `json:"variant" gorm:"index"`
+	// 生成时的主题（预设主题、节气或节日），没有主题时为空
+	Theme string `json:"theme"`
+	// 按预设内容或预设主题产出时对应的预设
+	OverrideID *uint `json:"override_id" gorm:"index"`
+	// 按学习路径产出时对应的路径名和单元序号（从 1 开始）
+	Curriculum string `json:"curriculum" gorm:"index"`
+	Unit       int    `json:"unit"`
+	// 管理员固定到该日期的内容，优先展示，不会被自动生成替换
+	Pinned bool `json:"pinned" gorm:"index;default:false"`
+	// 同类型同一天的版本号，从 1 开始；出处存疑的记录不参与编号，为 0
+	Version int `json:"version" gorm:"index"`
+	// 当天已被重新生成的内容替换的旧版本，保留供查看但不再对外展示
+	Superseded bool           `json:"superseded" gorm:"index;default:false"`
+	Date       time.Time      `json:"date" gorm:"type:date;not null"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+const (
+	VerificationVerified   = "verified"
+	VerificationUnverified = "unverified"
+	VerificationDisputed   = "disputed"
+)
+
 type LearnedContent struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Type      string    `json:"type" gorm:"not null"`
@@ -80,31 +112,73 @@ type APIResponse struct {
 }
 
 type TodayLearningData struct {
-	Type           string    `json:"type"`
-	TypeName       string    `json:"type_name"`
-	Content        string    `json:"content"`
-	Interpretation string    `json:"interpretation"`
-	KeyWords       []string  `json:"key_words"`
-	Date           string    `json:"date"`
-	FromCache      bool      `json:"from_cache"`
+	ID             uint     `json:"id"`
+	Type           string   `json:"type"`
+	TypeName       string   `json:"type_name"`
+	Content        string   `json:"content"`
+	Interpretation string   `json:"interpretation"`
+	KeyWords       []string `json:"key_words"`
+	SourceID       string   `json:"source_id,omitempty"`
+	Verification   string   `json:"verification"`
+	Theme          string   `json:"theme,omitempty"`
+	Curriculum     string   `json:"curriculum,omitempty"`
+	Unit           int      `json:"unit,omitempty"`
+	Date           string   `json:"date"`
+	FromCache      bool     `json:"from_cache"`
 }
 
 type LearningHistoryData struct {
-	Total   int                    `json:"total"`
-	Records []LearningHistoryItem  `json:"records"`
+	Total   int                   `json:"total"`
+	Records []LearningHistoryItem `json:"records"`
+}
+
+// LearningVersionsData 是某一天的全部版本，Published 为当前展示的版本号
+type LearningVersionsData struct {
+	Type      string                `json:"type"`
+	TypeName  string                `json:"type_name"`
+	Date      string                `json:"date"`
+	Published int                   `json:"published"`
+	Versions  []LearningVersionItem `json:"versions"`
+}
+
+type LearningVersionItem struct {
+	Version        int      `json:"version"`
+	Published      bool     `json:"published"`
+	Content        string   `json:"content"`
+	Interpretation string   `json:"interpretation"`
+	KeyWords       []string `json:"key_words"`
+	SourceID       string   `json:"source_id,omitempty"`
+	CreatedAt      string   `json:"created_at"`
 }
 
 type LearningHistoryItem struct {
+	ID             uint     `json:"id"`
 	Type           string   `json:"type"`
 	TypeName       string   `json:"type_name"`
 	Content        string   `json:"content"`
 	Interpretation string   `json:"interpretation"`
 	KeyWords       []string `json:"key_words"`
+	SourceID       string   `json:"source_id,omitempty"`
 	Date           string   `json:"date"`
 }
 
 type UserStatsData struct {
 	Stats map[string]TypeStats `json:"stats"`
+	// 登录用户自己的统计，匿名访问时为空
+	User map[string]UserTypeStats `json:"user,omitempty"`
+}
+
+type UserTypeStats struct {
+	TypeName  string `json:"type_name"`
+	Viewed    int    `json:"viewed"`
+	Studied   int    `json:"studied"`
+	Favorites int    `json:"favorites"`
+	Notes     int    `json:"notes"`
+	// 今天需要复习的内容数，包括从未复习过的新内容
+	DueReviews int `json:"due_reviews"`
+	// 提交过的练习次数和全部答题的正确率（0-1）
+	Quizzes      int     `json:"quizzes"`
+	QuizAccuracy float64 `json:"quiz_accuracy"`
 }
 
 type TypeStats struct {
@@ -125,6 +199,8 @@ type VolcanoAPIRequest struct {
 	Temperature    float64         `json:"temperature"`
 	MaxTokens      int             `json:"max_tokens"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
 }
 
 type ResponseFormat struct {
@@ -134,23 +210,55 @@ type ResponseFormat struct {
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// 思考模型返回的推理过程，只保存在生成记录中，不对外展示
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type VolcanoAPIResponse struct {
+	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   Usage    `json:"usage"`
+	// 原始响应正文，流式调用时为拼接后的文本
+	Raw string `json:"-"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 type Choice struct {
 	Message Message `json:"message"`
 }
 
+type AttributionCheck struct {
+	Correct            bool   `json:"correct"`
+	Reason             string `json:"reason"`
+	CorrectAttribution string `json:"correct_attribution,omitempty"`
+}
+
+type VolcanoStreamChunk struct {
+	Model   string         `json:"model"`
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage"`
+}
+
+type StreamChoice struct {
+	Delta Message `json:"delta"`
+}
+
 type AIContent struct {
-	Proverb string `json:"proverb,omitempty"`
-	Poem string `json:"poem,omitempty"`
-	TCMText string `json:"tcm_text,omitempty"`
-	Interpretation string `json:"interpretation"`
-	KeyWords []KeyWordItem `json:"key_words,omitempty"`
-	KeyConcepts []KeyConceptItem `json:"key_concepts,omitempty"`
+	Proverb        string           `json:"proverb,omitempty"`
+	Poem           string           `json:"poem,omitempty"`
+	TCMText        string           `json:"tcm_text,omitempty"`
+	Interpretation string           `json:"interpretation"`
+	KeyWords       []KeyWordItem    `json:"key_words,omitempty"`
+	KeyConcepts    []KeyConceptItem `json:"key_concepts,omitempty"`
 }
 
 type KeyWordItem struct {
@@ -168,12 +276,23 @@ type LearningContent struct {
 	Content        string
 	Interpretation string
 	KeyWords       []string
-	Date           time.Time
+	// 内容来自本地语料时记录语料ID，模型自由生成时为空
+	SourceID         string
+	Verification     string
+	VerificationNote string
+	AttemptID        *uint
+	Tier             string
+	Variant          string
+	Theme            string
+	OverrideID       *uint
+	Curriculum       string
+	Unit             int
+	Date             time.Time
 }
 
 func (lc *LearningContent) Validate() []string {
 	var errors []string
-	
+
 	if strings.TrimSpace(lc.Content) == "" {
 		errors = append(errors, "内容不能为空")
 	}
@@ -183,10 +302,640 @@ func (lc *LearningContent) Validate() []string {
 	if len(lc.KeyWords) == 0 {
 		errors = append(errors, "关键词不能为空")
 	}
-	
+
 	return errors
 }
 
 func (lc *LearningContent) FormatKeyWords() string {
 	return strings.Join(lc.KeyWords, ",")
-}
\ No newline at end of file
+}
+
+// 模型调用的用途
+const (
+	PurposeGenerate = "generate"
+	PurposeExplain  = "explain"
+	PurposeVerify   = "verify"
+	// 提示词评测发起的调用，计入用量但不产生学习记录
+	PurposeEval = "eval"
+	// 请模型根据学习记录出练习题
+	PurposeQuiz = "quiz"
+)
+
+// 模型调用结果
+const (
+	OutcomeOK           = "ok"
+	OutcomeRequestError = "request_error"
+	OutcomeParseError   = "parse_error"
+	// 解析成功但与已学内容重复，被生成流程拒绝
+	OutcomeDuplicate = "duplicate"
+)
+
+// GenerationAttempt 记录每一次模型调用的参数、耗时、用量和解析结果
+type GenerationAttempt struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Type             string    `json:"type" gorm:"not null;index"`
+	Purpose          string    `json:"purpose" gorm:"not null;index"`
+	Model            string    `json:"model"`
+	PromptVersion    string    `json:"prompt_version"`
+	Variant          string    `json:"variant" gorm:"index"`
+	Temperature      float64   `json:"temperature"`
+	MaxTokens        int       `json:"max_tokens"`
+	Stream           bool      `json:"stream"`
+	LatencyMs        int64     `json:"latency_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	RawResponse      string    `json:"raw_response" gorm:"type:text"`
+	ReasoningContent string    `json:"reasoning_content" gorm:"type:text"`
+	Outcome          string    `json:"outcome" gorm:"not null;index"`
+	Error            string    `json:"error" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at" gorm:"index"`
+}
+
+type UsageRow struct {
+	Period           string `json:"period"`
+	Type             string `json:"type"`
+	Calls            int64  `json:"calls"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+}
+
+type BudgetStatus struct {
+	DailyLimit   int64 `json:"daily_limit"`
+	MonthlyLimit int64 `json:"monthly_limit"`
+	TodayUsed    int64 `json:"today_used"`
+	MonthUsed    int64 `json:"month_used"`
+	Exceeded     bool  `json:"exceeded"`
+}
+
+type UsageData struct {
+	Daily   []UsageRow   `json:"daily"`
+	Monthly []UsageRow   `json:"monthly"`
+	Budget  BudgetStatus `json:"budget"`
+}
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+type GenerationJob struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:32"`
+	Type       string     `json:"type" gorm:"not null;index"`
+	Status     string     `json:"status" gorm:"not null;index"`
+	RecordID   *uint      `json:"record_id"`
+	Error      string     `json:"error" gorm:"type:text"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type JobData struct {
+	JobID     string             `json:"job_id"`
+	Type      string             `json:"type"`
+	TypeName  string             `json:"type_name"`
+	Status    string             `json:"status"`
+	StatusURL string             `json:"status_url"`
+	Error     string             `json:"error,omitempty"`
+	Result    *TodayLearningData `json:"result,omitempty"`
+	CreatedAt string             `json:"created_at"`
+}
+
+// Feedback 是用户对某条学习记录是否有帮助的反馈
+type Feedback struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	RecordID uint   `json:"record_id" gorm:"not null;index"`
+	Type     string `json:"type" gorm:"not null;index"`
+	Helpful  bool   `json:"helpful"`
+	// 反馈人，登录用户为 user:<ID>，匿名访问为 ip:<客户端IP>；同一人对同一条记录只保留最后一次反馈
+	Voter     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FeedbackRequest struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+// PromptPromotion 记录某类型被提升为唯一版本的提示词实验版本，存在时不再按权重分流
+type PromptPromotion struct {
+	Type       string    `json:"type" gorm:"primaryKey"`
+	Variant    string    `json:"variant" gorm:"not null"`
+	PromotedAt time.Time `json:"promoted_at"`
+}
+
+type PromoteVariantRequest struct {
+	Variant string `json:"variant" binding:"required"`
+}
+
+// VariantStats 是一个提示词版本的实验数据，比例均以 0~1 表示
+type VariantStats struct {
+	Variant        string  `json:"variant"`
+	Weight         int     `json:"weight"`
+	Calls          int64   `json:"calls"`
+	RequestErrors  int64   `json:"request_errors"`
+	ParseErrors    int64   `json:"parse_errors"`
+	Duplicates     int64   `json:"duplicates"`
+	Records        int64   `json:"records"`
+	Helpful        int64   `json:"helpful"`
+	Unhelpful      int64   `json:"unhelpful"`
+	ParseErrorRate float64 `json:"parse_error_rate"`
+	DuplicateRate  float64 `json:"duplicate_rate"`
+	HelpfulRate    float64 `json:"helpful_rate"`
+}
+
+type ExperimentData struct {
+	Type     string         `json:"type"`
+	TypeName string         `json:"type_name"`
+	Promoted string         `json:"promoted,omitempty"`
+	Variants []VariantStats `json:"variants"`
+}
+
+// 审计日志中的操作和实体
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditRegenerate = "regenerate"
+	AuditPin        = "pin"
+	AuditRestore    = "restore"
+
+	// 非管理员发起的变更的操作人
+	ActorSystem = "system"
+	ActorDebug  = "debug"
+
+	EntityLearningRecord = "learning_record"
+	EntityLearnedContent = "learned_content"
+	EntityOverride       = "override"
+	EntityCurriculum     = "curriculum_progress"
+)
+
+// AuditEvent 是一条只追加的审计日志（数据库触发器禁止修改和删除）：谁在什么时候
+// 因为什么对哪条数据做了什么，Before、After 为变更前后的 JSON 快照
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Actor     string    `json:"actor" gorm:"index;not null"`
+	Action    string    `json:"action" gorm:"index;not null"`
+	Entity    string    `json:"entity" gorm:"index:idx_audit_entity;not null"`
+	EntityID  uint      `json:"entity_id" gorm:"index:idx_audit_entity"`
+	Before    string    `json:"before,omitempty" gorm:"type:text"`
+	After     string    `json:"after,omitempty" gorm:"type:text"`
+	Reason    string    `json:"reason,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditQuery 是审计日志的筛选条件，零值表示不筛选
+type AuditQuery struct {
+	Entity   string
+	EntityID uint
+	Actor    string
+	Action   string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// RestoreRequest 指定恢复审计日志中的哪个快照，默认 before（撤销该次变更）
+type RestoreRequest struct {
+	Snapshot string `json:"snapshot"`
+}
+
+// AdminRecordRequest 用于管理接口创建或修改学习记录，修改时只更新传入的字段
+type AdminRecordRequest struct {
+	Type             *string  `json:"type"`
+	Content          *string  `json:"content"`
+	Interpretation   *string  `json:"interpretation"`
+	KeyWords         []string `json:"key_words"`
+	SourceID         *string  `json:"source_id"`
+	Verification     *string  `json:"verification"`
+	VerificationNote *string  `json:"verification_note"`
+	// 日期格式为 2006-01-02，创建时缺省为今天
+	Date *string `json:"date"`
+	// 只能传 false 取消固定，固定内容使用 PinRequest
+	Pinned *bool `json:"pinned"`
+}
+
+type AdminLearnedRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// PinRequest 把已有记录或新内容固定到某一天，RecordID 和 Content 二选一
+type PinRequest struct {
+	Date           string   `json:"date" binding:"required"`
+	RecordID       *uint    `json:"record_id"`
+	Type           string   `json:"type"`
+	Content        string   `json:"content"`
+	Interpretation string   `json:"interpretation"`
+	KeyWords       []string `json:"key_words"`
+}
+
+type AdminListData struct {
+	Total int64       `json:"total"`
+	Items interface{} `json:"items"`
+}
+
+// Override 是管理员为某类型某一天预先安排的内容：直接给出内容（Content 非空），
+// 或只给出主题（Theme）交由模型按主题生成
+type Override struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Type string `json:"type" gorm:"uniqueIndex:idx_override_type_date;not null"`
+	// 日期格式为 2006-01-02
+	Date           string    `json:"date" gorm:"uniqueIndex:idx_override_type_date;not null"`
+	Content        string    `json:"content" gorm:"type:text"`
+	Interpretation string    `json:"interpretation" gorm:"type:text"`
+	KeyWords       string    `json:"key_words" gorm:"type:text"`
+	Theme          string    `json:"theme"`
+	Note           string    `json:"note" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (o *Override) HasContent() bool {
+	return strings.TrimSpace(o.Content) != ""
+}
+
+type OverrideRequest struct {
+	Type           string   `json:"type" binding:"required"`
+	Date           string   `json:"date" binding:"required"`
+	Content        string   `json:"content"`
+	Interpretation string   `json:"interpretation"`
+	KeyWords       []string `json:"key_words"`
+	Theme          string   `json:"theme"`
+	Note           string   `json:"note"`
+}
+
+// CalendarDay 是管理接口中某一天的节气、节日和已有的预设，便于提前安排内容
+type CalendarDay struct {
+	Date      string     `json:"date"`
+	Occasions []string   `json:"occasions,omitempty"`
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// CurriculumProgress 记录一条学习路径的进度，以及它是否为该类型当前启用的路径
+type CurriculumProgress struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Path string `json:"path" gorm:"uniqueIndex;not null"`
+	Type string `json:"type" gorm:"index;not null"`
+	// 同一类型最多只有一条启用的路径
+	Active bool `json:"active" gorm:"index;default:false"`
+	// 已学完的单元数
+	Position int `json:"position"`
+	// 最近一次学习该路径的日期，格式为 2006-01-02
+	LastDate  string    `json:"last_date"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NextUnit 返回 day 这天要学的单元序号（从 1 开始）：当天已学过时仍是当天的单元，
+// 便于重新生成；全部学完时返回 0
+func (p *CurriculumProgress) NextUnit(day string, total int) int {
+	if p.LastDate == day && p.Position > 0 {
+		return p.Position
+	}
+	if p.Position < total {
+		return p.Position + 1
+	}
+	return 0
+}
+
+type CurriculumUnitItem struct {
+	Seq    int    `json:"seq"`
+	Title  string `json:"title"`
+	Topic  string `json:"topic,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Source string `json:"source,omitempty"`
+	// done、today、next 或 pending
+	Status string `json:"status"`
+}
+
+// CurriculumData 是一条学习路径及其进度，NextUnit 为 0 表示已全部学完
+type CurriculumData struct {
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	TypeName    string               `json:"type_name"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Active      bool                 `json:"active"`
+	Total       int                  `json:"total"`
+	Position    int                  `json:"position"`
+	NextUnit    int                  `json:"next_unit"`
+	LastDate    string               `json:"last_date,omitempty"`
+	Units       []CurriculumUnitItem `json:"units,omitempty"`
+}
+
+// CurriculumActivateRequest 设置某类型启用的学习路径，Path 为空表示停用
+type CurriculumActivateRequest struct {
+	Path string `json:"path"`
+}
+
+// CurriculumProgressRequest 把学习路径的进度设为已学完 Position 个单元
+type CurriculumProgressRequest struct {
+	Position *int `json:"position" binding:"required"`
+}
+
+type User struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Username     string     `json:"username" gorm:"uniqueIndex;not null"`
+	PasswordHash string     `json:"-" gorm:"not null"`
+	DisplayName  string     `json:"display_name"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RefreshToken 记录签发过的 refresh token，每次刷新都会吊销旧令牌并签发新令牌
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	TokenID   string     `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// UserRecord 是用户与一条学习记录的关系：看过的记录构成个人学习历史，
+// 另外记录是否已学会、是否收藏和用户的笔记
+type UserRecord struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_user_record;not null"`
+	RecordID    uint      `json:"record_id" gorm:"uniqueIndex:idx_user_record;not null"`
+	Type        string    `json:"type" gorm:"index;not null"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	// 各项标记的时间在取消标记时清空
+	Studied       bool       `json:"studied" gorm:"index;default:false"`
+	StudiedAt     *time.Time `json:"studied_at"`
+	Favorite      bool       `json:"favorite" gorm:"index;default:false"`
+	FavoritedAt   *time.Time `json:"favorited_at"`
+	Note          string     `json:"note" gorm:"type:text"`
+	NoteUpdatedAt *time.Time `json:"note_updated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// UserRecordQuery 是收藏、笔记列表的筛选条件，Query 同时匹配内容、释义、关键词和笔记
+type UserRecordQuery struct {
+	Type     string
+	Query    string
+	Favorite bool
+	HasNote  bool
+	Limit    int
+	Offset   int
+}
+
+// UserRecordData 是用户对一条学习记录的学习状态及记录内容
+type UserRecordData struct {
+	Record        LearningHistoryItem `json:"record"`
+	Studied       bool                `json:"studied"`
+	StudiedAt     *time.Time          `json:"studied_at,omitempty"`
+	Favorite      bool                `json:"favorite"`
+	FavoritedAt   *time.Time          `json:"favorited_at,omitempty"`
+	Note          string              `json:"note"`
+	NoteUpdatedAt *time.Time          `json:"note_updated_at,omitempty"`
+	LastSeenAt    *time.Time          `json:"last_seen_at,omitempty"`
+}
+
+type UserRecordListData struct {
+	Total int64            `json:"total"`
+	Items []UserRecordData `json:"items"`
+}
+
+type NoteRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// AuthData 是注册、登录和刷新令牌的返回结果，有效期单位为秒
+type AuthData struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+	User             *User  `json:"user"`
+}
+
+// ReviewState 是用户对一条学习记录的间隔复习进度（SM-2），首次复习时创建
+type ReviewState struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	UserID      uint    `json:"user_id" gorm:"uniqueIndex:idx_review_user_record;not null"`
+	RecordID    uint    `json:"record_id" gorm:"uniqueIndex:idx_review_user_record;not null"`
+	Type        string  `json:"type" gorm:"index;not null"`
+	Repetitions int     `json:"repetitions"`
+	Interval    int     `json:"interval_days"`
+	Ease        float64 `json:"ease"`
+	Lapses      int     `json:"lapses"`
+	Reviews     int     `json:"reviews"`
+	LastGrade   int     `json:"last_grade"`
+	// 下次复习的日期（当天零点）
+	DueAt          time.Time  `json:"due_at" gorm:"index"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ReviewItem 是复习队列中的一条内容，New 表示从未复习过
+type ReviewItem struct {
+	Record       LearningHistoryItem `json:"record"`
+	New          bool                `json:"new"`
+	DueAt        *time.Time          `json:"due_at,omitempty"`
+	IntervalDays int                 `json:"interval_days"`
+	Repetitions  int                 `json:"repetitions"`
+	Ease         float64             `json:"ease"`
+	Lapses       int                 `json:"lapses"`
+	Reviews      int                 `json:"reviews"`
+}
+
+// ReviewQueueData 是今天需要复习的内容，Due 为到期总数，Items 最多返回 limit 条
+type ReviewQueueData struct {
+	Due   int          `json:"due"`
+	Items []ReviewItem `json:"items"`
+}
+
+// ReviewRequest 提交一次复习的回忆评分，0-5 分，3 分及以上视为记得
+type ReviewRequest struct {
+	Grade *int `json:"grade" binding:"required"`
+}
+
+// Quiz 是根据一条学习记录出的练习题，首次请求时生成并保存，记录被修改后重新出题
+type Quiz struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	RecordID uint   `json:"record_id" gorm:"uniqueIndex:idx_quiz_record_version;not null"`
+	Type     string `json:"type" gorm:"index;not null"`
+	// 出题时学习记录的更新时间。记录每修改一次就另出一份题目，旧题目保留，按旧题目提交的答案仍按旧题目判分
+	RecordUpdatedAt time.Time `json:"record_updated_at" gorm:"uniqueIndex:idx_quiz_record_version"`
+	// 本地出的题目（含答案），JSON 数组
+	Questions string `json:"-" gorm:"type:text;not null"`
+	// 模型出的题目，首次请求时生成并缓存，为空表示尚未生成
+	ModelQuestions string    `json:"-" gorm:"type:text"`
+	ModelAttemptID *uint     `json:"model_attempt_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// QuizQuestionItem 是返回给用户的题目，不含答案；填空题没有选项
+type QuizQuestionItem struct {
+	ID      string   `json:"id"`
+	Kind    string   `json:"kind"`
+	Prompt  string   `json:"prompt"`
+	Options []string `json:"options,omitempty"`
+}
+
+type QuizData struct {
+	ID        uint               `json:"id"`
+	RecordID  uint               `json:"record_id"`
+	Type      string             `json:"type"`
+	TypeName  string             `json:"type_name"`
+	Date      string             `json:"date"`
+	Questions []QuizQuestionItem `json:"questions"`
+	// 是否包含模型出的题目
+	Model bool `json:"model"`
+}
+
+// QuizResult 是登录用户提交的一次练习成绩，匿名用户的成绩不保存
+type QuizResult struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	UserID   uint   `json:"user_id" gorm:"index;not null"`
+	QuizID   uint   `json:"quiz_id" gorm:"index;not null"`
+	RecordID uint   `json:"record_id" gorm:"index"`
+	Type     string `json:"type" gorm:"index;not null"`
+	Correct  int    `json:"correct"`
+	Total    int    `json:"total"`
+	// 逐题判分结果，JSON 数组
+	Answers   string    `json:"-" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+type QuizAnswer struct {
+	QuestionID string `json:"question_id" binding:"required"`
+	Answer     string `json:"answer" binding:"max=500"`
+}
+
+// QuizAnswerRequest 提交练习答案，没有提交的题目算错
+type QuizAnswerRequest struct {
+	Answers []QuizAnswer `json:"answers" binding:"required,min=1,max=50,dive"`
+}
+
+type QuizAnswerResult struct {
+	QuestionID    string `json:"question_id"`
+	Answer        string `json:"answer"`
+	Correct       bool   `json:"correct"`
+	CorrectAnswer string `json:"correct_answer"`
+	Explanation   string `json:"explanation,omitempty"`
+}
+
+type QuizResultData struct {
+	// 保存的成绩ID，匿名提交时为 0
+	ID       uint               `json:"id,omitempty"`
+	QuizID   uint               `json:"quiz_id"`
+	RecordID uint               `json:"record_id"`
+	Type     string             `json:"type"`
+	TypeName string             `json:"type_name"`
+	Correct  int                `json:"correct"`
+	Total    int                `json:"total"`
+	Score    int                `json:"score"`
+	Saved    bool               `json:"saved"`
+	Answers  []QuizAnswerResult `json:"answers"`
+	// 提交时间，匿名提交时为空
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+type QuizResultListData struct {
+	Results []QuizResultData `json:"results"`
+	Total   int64            `json:"total"`
+}
+
+// StatsDay 是学习日历中的一天，Types 为当天有内容的学习类型
+type StatsDay struct {
+	Date  string   `json:"date"`
+	Types []string `json:"types"`
+	Count int      `json:"count"`
+}
+
+type MonthStats struct {
+	Month string `json:"month"`
+	// 当月的内容条数和有内容的天数
+	Count int `json:"count"`
+	Days  int `json:"days"`
+}
+
+type DistributionItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// CalendarStats 是一年的学习日历。连续天数按全部日期计算，不限于这一年；
+// 今天还没有内容时，截至昨天的连续天数仍算作当前连续天数
+type CalendarStats struct {
+	Days          []StatsDay         `json:"days"`
+	ActiveDays    int                `json:"active_days"`
+	CurrentStreak int                `json:"current_streak"`
+	LongestStreak int                `json:"longest_streak"`
+	Monthly       []MonthStats       `json:"monthly"`
+	Keywords      []DistributionItem `json:"keywords"`
+	Authors       []DistributionItem `json:"authors"`
+	Sources       []DistributionItem `json:"sources"`
+}
+
+type StatsCalendarData struct {
+	Year   int           `json:"year"`
+	Type   string        `json:"type,omitempty"`
+	Global CalendarStats `json:"global"`
+	// 登录用户自己的日历，按首次查看内容的日期统计，匿名访问时为空
+	User *CalendarStats `json:"user,omitempty"`
+}
+
+// GlossaryTermItem 是词汇表中的一个词条，Records 为出现过的记录数
+type GlossaryTermItem struct {
+	Term     string   `json:"term"`
+	Key      string   `json:"key"`
+	Types    []string `json:"types"`
+	Meanings []string `json:"meanings"`
+	Records  int      `json:"records"`
+}
+
+type GlossaryListData struct {
+	Terms []GlossaryTermItem `json:"terms"`
+	Total int                `json:"total"`
+}
+
+type GlossaryMeaning struct {
+	Meaning string `json:"meaning"`
+	Count   int    `json:"count"`
+}
+
+// GlossaryOccurrence 是词语在一条记录中的出现，Context 为原文中包含该词的句子
+type GlossaryOccurrence struct {
+	RecordID uint   `json:"record_id"`
+	Type     string `json:"type"`
+	TypeName string `json:"type_name"`
+	Date     string `json:"date"`
+	Word     string `json:"word"`
+	Meaning  string `json:"meaning"`
+	Context  string `json:"context"`
+}
+
+type GlossaryTermData struct {
+	Term        string               `json:"term"`
+	Key         string               `json:"key"`
+	Types       []string             `json:"types"`
+	Meanings    []GlossaryMeaning    `json:"meanings"`
+	Occurrences []GlossaryOccurrence `json:"occurrences"`
+}
@@ -0,0 +1,66 @@
+package quiz
+
+import (
+	"everyday-study-backend/internal/corpus"
+	"unicode/utf8"
+)
+
+// typoRunes 表示每多少个字允许错一个字，短答案（如两个字的人名）必须完全正确
+const typoRunes = 4
+
+// Grade 判断回答是否正确。比较前统一全角半角、大小写并去掉空白和标点；
+// 较长的答案允许少量错字。选择题的回答只有离正确选项最近、且不同样接近其他选项时才算对
+func Grade(q Question, answer string) bool {
+	given := corpus.Fold(answer)
+	want := corpus.Fold(q.Answer)
+	if given == "" {
+		return false
+	}
+	if given == want {
+		return true
+	}
+
+	distance := editDistance(given, want)
+	if distance > utf8.RuneCountInString(want)/typoRunes {
+		return false
+	}
+	for _, option := range q.Options {
+		o := corpus.Fold(option)
+		if o != want && editDistance(given, o) <= distance {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance 按字计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,290 @@
+package quiz
+
+import (
+	"everyday-study-backend/internal/corpus"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 题型
+const (
+	KindCloze          = "cloze"
+	KindMeaning        = "meaning"
+	KindAuthor         = "author"
+	KindSource         = "source"
+	KindInterpretation = "interpretation"
+	// 模型出的选择题
+	KindModel = "model"
+)
+
+const (
+	maxCloze    = 3
+	maxMeaning  = 3
+	optionCount = 4
+	blank       = "____"
+)
+
+// 中医语料之外常见的典籍，干扰项不足时补充
+var classicBooks = []string{"黄帝内经", "伤寒论", "金匮要略", "神农本草经", "难经", "温病条辨", "本草纲目", "千金要方"}
+
+// Question 是一道题。Answer 和 Explanation 保存在服务端，判分后才返回给用户
+type Question struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind"`
+	Prompt      string   `json:"prompt"`
+	Options     []string `json:"options,omitempty"`
+	Answer      string   `json:"answer"`
+	Explanation string   `json:"explanation,omitempty"`
+}
+
+// Material 是出题用的一条内容：当天的学习记录，或用来挑选干扰项的其他记录
+type Material struct {
+	ID             uint
+	Type           string
+	Content        string
+	Interpretation string
+	KeyWords       []string
+}
+
+func parseKeywords(words []string) []corpus.Keyword {
+	var result []corpus.Keyword
+	for _, w := range words {
+		if kw, ok := corpus.ParseKeyword(w); ok {
+			result = append(result, kw)
+		}
+	}
+	return result
+}
+
+// Build 根据内容和关键词出题，pool 是同类型的其他内容，用来挑选干扰项。
+// 随机顺序以记录ID为种子，同样的输入总是得到同样的题目。
+func Build(m Material, pool []Material) []Question {
+	rng := rand.New(rand.NewSource(int64(m.ID)))
+	body := m.Content
+	attr, hasAttr := corpus.ParseAttribution(m.Content)
+	if hasAttr {
+		body = attr.Text
+	}
+	keywords := parseKeywords(m.KeyWords)
+	others := candidates(m, body, pool)
+
+	var questions []Question
+	questions = append(questions, clozeQuestions(m.Type, body, keywords)...)
+	questions = append(questions, meaningQuestions(rng, keywords, others)...)
+	switch m.Type {
+	case "chinese":
+		if hasAttr {
+			questions = append(questions, authorQuestion(rng, attr, others)...)
+		}
+	case "tcm":
+		if hasAttr {
+			questions = append(questions, sourceQuestion(rng, attr, others)...)
+		}
+	case "english":
+		questions = append(questions, interpretationQuestion(rng, body, m.Interpretation, others)...)
+	}
+
+	for i := range questions {
+		questions[i].ID = fmt.Sprintf("q%d", i+1)
+	}
+	return questions
+}
+
+// candidates 合并其他记录和本地语料，去掉与当天内容相同的条目
+func candidates(m Material, body string, pool []Material) []Material {
+	result := make([]Material, 0, len(pool))
+	for _, p := range pool {
+		if p.Type == m.Type && p.ID != m.ID {
+			result = append(result, p)
+		}
+	}
+	for _, item := range corpus.Items(m.Type) {
+		if corpus.ContainsLearned(item.Text, []string{body}) {
+			continue
+		}
+		result = append(result, Material{
+			Type:           item.Type,
+			Content:        item.Content(),
+			Interpretation: item.Interpretation,
+			KeyWords:       item.KeyWords,
+		})
+	}
+	return result
+}
+
+// clozeQuestions 把原文中的关键词挖空，英文按词首匹配，以便覆盖 catch/catches 这类变形
+func clozeQuestions(learningType string, body string, keywords []corpus.Keyword) []Question {
+	var questions []Question
+	for _, kw := range keywords {
+		if len(questions) >= maxCloze {
+			break
+		}
+		if utf8.RuneCountInString(kw.Word) >= utf8.RuneCountInString(body) {
+			continue
+		}
+
+		var answer, blanked string
+		if learningType == "english" {
+			pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(kw.Word) + `\w*`)
+			answer = pattern.FindString(body)
+			blanked = pattern.ReplaceAllLiteralString(body, blank)
+		} else if strings.Contains(body, kw.Word) {
+			answer = kw.Word
+			blanked = strings.ReplaceAll(body, kw.Word, blank)
+		}
+		if answer == "" {
+			continue
+		}
+
+		questions = append(questions, Question{
+			Kind:        KindCloze,
+			Prompt:      "补全原文：" + blanked,
+			Answer:      answer,
+			Explanation: fmt.Sprintf("「%s」：%s", answer, kw.Meaning),
+		})
+	}
+	return questions
+}
+
+// meaningQuestions 考关键词的释义，干扰项取自其他内容的关键词释义
+func meaningQuestions(rng *rand.Rand, keywords []corpus.Keyword, others []Material) []Question {
+	var pool []string
+	for _, o := range others {
+		for _, kw := range parseKeywords(o.KeyWords) {
+			pool = append(pool, kw.Meaning)
+		}
+	}
+
+	var questions []Question
+	for _, kw := range keywords {
+		if len(questions) >= maxMeaning {
+			break
+		}
+		options := choices(rng, kw.Meaning, pool)
+		if options == nil {
+			continue
+		}
+		questions = append(questions, Question{
+			Kind:    KindMeaning,
+			Prompt:  fmt.Sprintf("「%s」在文中的意思是？", kw.Word),
+			Options: options,
+			Answer:  kw.Meaning,
+		})
+	}
+	return questions
+}
+
+func authorQuestion(rng *rand.Rand, attr *corpus.Attribution, others []Material) []Question {
+	if attr.Author == "" {
+		return nil
+	}
+	var pool []string
+	for _, o := range others {
+		if a, ok := corpus.ParseAttribution(o.Content); ok {
+			pool = append(pool, a.Author)
+		}
+	}
+	options := choices(rng, attr.Author, pool)
+	if options == nil {
+		return nil
+	}
+
+	prompt := fmt.Sprintf("「%s」的作者是？", firstClause(attr.Text))
+	if attr.Work != "" {
+		prompt = fmt.Sprintf("《%s》的作者是？", attr.Work)
+	}
+	return []Question{{
+		Kind:        KindAuthor,
+		Prompt:      prompt,
+		Options:     options,
+		Answer:      attr.Author,
+		Explanation: strings.TrimSpace(fmt.Sprintf("%s %s 《%s》", attr.Dynasty, attr.Author, attr.Work)),
+	}}
+}
+
+func sourceQuestion(rng *rand.Rand, attr *corpus.Attribution, others []Material) []Question {
+	book := attr.Book()
+	if book == "" {
+		return nil
+	}
+	var pool []string
+	for _, o := range others {
+		if a, ok := corpus.ParseAttribution(o.Content); ok {
+			pool = append(pool, a.Book())
+		}
+	}
+	pool = append(pool, classicBooks...)
+	options := choices(rng, book, pool)
+	if options == nil {
+		return nil
+	}
+	return []Question{{
+		Kind:        KindSource,
+		Prompt:      fmt.Sprintf("「%s」出自哪部典籍？", firstClause(attr.Text)),
+		Options:     options,
+		Answer:      book,
+		Explanation: fmt.Sprintf("出自《%s》", attr.Work),
+	}}
+}
+
+func interpretationQuestion(rng *rand.Rand, body string, interpretation string, others []Material) []Question {
+	interpretation = strings.TrimSpace(interpretation)
+	if interpretation == "" {
+		return nil
+	}
+	var pool []string
+	for _, o := range others {
+		pool = append(pool, o.Interpretation)
+	}
+	options := choices(rng, interpretation, pool)
+	if options == nil {
+		return nil
+	}
+	return []Question{{
+		Kind:    KindInterpretation,
+		Prompt:  fmt.Sprintf("“%s”的意思是？", body),
+		Options: options,
+		Answer:  interpretation,
+	}}
+}
+
+// choices 从候选中挑出与答案不同的干扰项，和答案一起打乱顺序；干扰项不足两个时返回 nil
+func choices(rng *rand.Rand, answer string, pool []string) []string {
+	seen := map[string]bool{corpus.Fold(answer): true}
+	var distractors []string
+	for _, c := range pool {
+		c = strings.TrimSpace(c)
+		key := corpus.Fold(c)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		distractors = append(distractors, c)
+	}
+	if len(distractors) < 2 {
+		return nil
+	}
+
+	rng.Shuffle(len(distractors), func(i, j int) {
+		distractors[i], distractors[j] = distractors[j], distractors[i]
+	})
+	if len(distractors) > optionCount-1 {
+		distractors = distractors[:optionCount-1]
+	}
+	options := append([]string{answer}, distractors...)
+	rng.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options
+}
+
+// firstClause 取原文第一句，用于题干
+func firstClause(text string) string {
+	if i := strings.IndexAny(text, "，。；！？,.;!?"); i > 0 {
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,58 @@
+package review
+
+import (
+	"math"
+	"time"
+)
+
+// 回忆评分沿用 SM-2 的 0-5 分：5 完全记得，3 勉强想起，低于 3 视为遗忘
+const (
+	MinGrade  = 0
+	MaxGrade  = 5
+	PassGrade = 3
+)
+
+const (
+	DefaultEase = 2.5
+	minEase     = 1.3
+)
+
+// State 是一条内容的复习进度
+type State struct {
+	// 连续答对的次数，遗忘后归零
+	Repetitions int
+	// 距下次复习的天数
+	Interval int
+	Ease     float64
+	// 遗忘的次数
+	Lapses int
+}
+
+// Next 按 SM-2 算法根据回忆评分计算新的复习进度，返回下次复习的日期（当天零点）
+func Next(s State, grade int, now time.Time) (State, time.Time) {
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+
+	if grade < PassGrade {
+		s.Repetitions = 0
+		s.Interval = 1
+		s.Lapses++
+	} else {
+		switch s.Repetitions {
+		case 0:
+			s.Interval = 1
+		case 1:
+			s.Interval = 6
+		default:
+			s.Interval = int(math.Round(float64(s.Interval) * s.Ease))
+		}
+		s.Repetitions++
+	}
+
+	q := float64(MaxGrade - grade)
+	s.Ease = math.Max(minEase, s.Ease+0.1-q*(0.08+q*0.02))
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return s, day.AddDate(0, 0, s.Interval)
+}
@@ -1,42 +1,31 @@
 package scheduler
 
 import (
-	"encoding/json"
-	"everyday-study-backend/internal/api"
-	"everyday-study-backend/internal/config"
-	"everyday-study-backend/internal/database"
+	"context"
+	"everyday-study-backend/internal/generator"
 	"everyday-study-backend/internal/models"
-	"fmt"
 	"log"
-	"strings"
 	"sync"
 	"time"
 )
 
 type ContentScheduler struct {
-	volcanoClient *api.VolcanoClient
-	ticker        *time.Ticker
-	quit          chan bool
-	wg            sync.WaitGroup
-	running       bool
-	mu            sync.Mutex
+	generator *generator.Generator
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	running   bool
+	mu        sync.Mutex
 }
 
-type ParsedContent struct {
-	Content        string
-	Interpretation string
-	KeyWords       []string
-}
-
-func NewContentScheduler(cfg *config.Config) *ContentScheduler {
+func NewContentScheduler(gen *generator.Generator) *ContentScheduler {
 	return &ContentScheduler{
-		volcanoClient: api.NewVolcanoClient(cfg),
-		quit:         make(chan bool, 1),
-		running:      false,
+		generator: gen,
+		running:   false,
 	}
 }
 
-func (cs *ContentScheduler) Start() {
+func (cs *ContentScheduler) Start(ctx context.Context) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	
@@ -46,6 +35,7 @@ func (cs *ContentScheduler) Start() {
 	}
 	
 	cs.running = true
+	cs.ctx, cs.cancel = context.WithCancel(ctx)
 	log.Println("🌙 内容定时器启动 - 每晚12点更新...")
 	
 	now := time.Now()
@@ -55,6 +45,7 @@ func (cs *ContentScheduler) Start() {
 	log.Printf("⏰ 下次内容更新时间: %s (还有 %v)", 
 		nextMidnight.Format("2006-01-02 00:00:00"), timeUntilMidnight)
 	
+	runCtx := cs.ctx
 	cs.wg.Add(1)
 	go func() {
 		defer cs.wg.Done()
@@ -64,21 +55,22 @@ func (cs *ContentScheduler) Start() {
 		select {
 		case <-timer.C:
 			log.Println("🕛 午夜12点到了，开始更新内容...")
-			cs.updateAllContent()
+			cs.updateAllContent(runCtx)
 			
-			cs.ticker = time.NewTicker(24 * time.Hour)
+			ticker := time.NewTicker(24 * time.Hour)
+			defer ticker.Stop()
 			
 			for {
 				select {
-				case <-cs.ticker.C:
+				case <-ticker.C:
 					log.Println("🕛 每日定时更新开始...")
-					cs.updateAllContent()
-				case <-cs.quit:
+					cs.updateAllContent(runCtx)
+				case <-runCtx.Done():
 					log.Println("📨 收到退出信号，停止定时任务")
 					return
 				}
 			}
-		case <-cs.quit:
+		case <-runCtx.Done():
 			timer.Stop()
 			log.Println("📨 收到退出信号，取消首次等待")
 			return
@@ -96,18 +88,8 @@ func (cs *ContentScheduler) Stop() {
 	}
 	
 	cs.running = false
-	
-	select {
-	case cs.quit <- true:
-		log.Println("📤 已发送退出信号")
-	default:
-		log.Println("⚠️  退出信号通道已满或已关闭")
-	}
-	
-	if cs.ticker != nil {
-		cs.ticker.Stop()
-		log.Println("⏹️  Ticker已停止")
-	}
+	cs.cancel()
+	log.Println("📤 已发送退出信号")
 	
 	done := make(chan bool, 1)
 	go func() {
@@ -123,7 +105,7 @@ func (cs *ContentScheduler) Stop() {
 	}
 }
 
-func (cs *ContentScheduler) updateAllContent() {
+func (cs *ContentScheduler) updateAllContent(ctx context.Context) {
 	cs.wg.Add(1)
 	defer cs.wg.Done()
 	
@@ -134,14 +116,12 @@ func (cs *ContentScheduler) updateAllContent() {
 	successCount := 0
 	
 	for _, learningType := range learningTypes {
-		select {
-		case <-cs.quit:
+		if ctx.Err() != nil {
 			log.Println("📨 更新过程中收到退出信号，停止更新")
 			return
-		default:
 		}
 		
-		err := cs.updateContentForType(learningType)
+		err := cs.updateContentForType(ctx, learningType)
 		if err != nil {
 			log.Printf("❌ 更新 %s 内容失败: %v", models.GetLearningTypeName(learningType), err)
 		} else {
@@ -149,7 +129,10 @@ func (cs *ContentScheduler) updateAllContent() {
 			successCount++
 		}
 		
-		time.Sleep(3 * time.Second)
+		select {
+		case <-time.After(3 * time.Second):
+		case <-ctx.Done():
+		}
 	}
 	
 	duration := time.Since(startTime)
@@ -157,184 +140,23 @@ func (cs *ContentScheduler) updateAllContent() {
 		successCount, len(learningTypes), duration)
 }
 
-func (cs *ContentScheduler) updateContentForType(learningType string) error {
+func (cs *ContentScheduler) updateContentForType(ctx context.Context, learningType string) error {
 	log.Printf("📚 正在更新 %s...", models.GetLearningTypeName(learningType))
-	
-	learnedContent, err := database.GetLearnedContent(learningType)
-	if err != nil {
-		return fmt.Errorf("获取已学习内容失败: %v", err)
-	}
-	
-	log.Printf("📝 已学习内容数量: %d", len(learnedContent))
-	
-	aiResponse, err := cs.volcanoClient.CallVolcanoAPI(learningType, learnedContent)
-	if err != nil {
-		return fmt.Errorf("调用AI API失败: %v", err)
-	}
-	
-	if len(aiResponse.Choices) == 0 {
-		return fmt.Errorf("AI API返回空响应")
-	}
-	
-	content := aiResponse.Choices[0].Message.Content
-	log.Printf("🤖 AI原始响应: %s", content[:min(100, len(content))]+"...")
-	
-	parsedContent, err := cs.parseAIContent(content, learningType)
-	if err != nil {
-		return fmt.Errorf("解析AI内容失败: %v", err)
-	}
-	
-	learningContent := models.LearningContent{
-		Type:           models.LearningType(learningType),
-		Content:        parsedContent.Content,
-		Interpretation: parsedContent.Interpretation,
-		KeyWords:       parsedContent.KeyWords,
-		Date:           time.Now(),
-	}
-	
-	_, err = database.SaveLearningRecord(learningType, learningContent)
-	if err != nil {
-		return fmt.Errorf("保存学习记录失败: %v", err)
-	}
-	
-	return nil
-}
-
-func (cs *ContentScheduler) parseAIContent(contentStr string, learningType string) (*ParsedContent, error) {
-	contentStr = strings.TrimPrefix(contentStr, "```json")
-	contentStr = strings.TrimSuffix(contentStr, "```")
-	contentStr = strings.TrimSpace(contentStr)
-
-	var aiData models.AIContent
-	err := json.Unmarshal([]byte(contentStr), &aiData)
-	if err == nil {
-		result := cs.extractContentFromAIData(&aiData, learningType)
-		if result != nil {
-			return result, nil
-		}
-	}
 
-	log.Printf("直接解析失败，尝试灵活解析: %v", err)
-
-	return cs.flexibleParseContent(contentStr, learningType)
+	_, err := cs.generator.Generate(ctx, learningType)
+	return err
 }
 
-func (cs *ContentScheduler) extractContentFromAIData(aiData *models.AIContent, learningType string) *ParsedContent {
-	result := &ParsedContent{
-		Interpretation: aiData.Interpretation,
-		KeyWords:       []string{},
-	}
-
-	switch strings.ToLower(learningType) {
-	case "english":
-		if aiData.Proverb != "" {
-			result.Content = aiData.Proverb
-		}
-		if len(aiData.KeyWords) > 0 {
-			for _, kw := range aiData.KeyWords {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
-			}
-		}
-	case "chinese":
-		if aiData.Poem != "" {
-			result.Content = aiData.Poem
-		}
-		if len(aiData.KeyWords) > 0 {
-			for _, kw := range aiData.KeyWords {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kw.Word, kw.Meaning))
-			}
-		}
-	case "tcm":
-		if aiData.TCMText != "" {
-			result.Content = aiData.TCMText
-		}
-		if len(aiData.KeyConcepts) > 0 {
-			for _, kc := range aiData.KeyConcepts {
-				result.KeyWords = append(result.KeyWords, fmt.Sprintf("%s: %s", kc.Concept, kc.Meaning))
-			}
-		}
-	}
-
-	if result.Content == "" || result.Interpretation == "" {
-		return nil
-	}
-
-	return result
-}
-
-func (cs *ContentScheduler) flexibleParseContent(contentStr string, learningType string) (*ParsedContent, error) {
-	var rawContent map[string]interface{}
-	err := json.Unmarshal([]byte(contentStr), &rawContent)
-	if err != nil {
-		return nil, fmt.Errorf("无法解析JSON内容: %v", err)
-	}
-
-	result := &ParsedContent{
-		KeyWords: []string{},
-	}
-
-	switch strings.ToLower(learningType) {
-	case "english":
-		result.Content = cs.getStringValue(rawContent, "proverb")
-		result.Interpretation = cs.getStringValue(rawContent, "interpretation")
-		result.KeyWords = cs.parseKeyItems(rawContent, "key_words", "word", "meaning")
-
-	case "chinese":
-		result.Content = cs.getStringValue(rawContent, "poem")
-		result.Interpretation = cs.getStringValue(rawContent, "interpretation")
-		result.KeyWords = cs.parseKeyItems(rawContent, "key_words", "word", "meaning")
-
-	case "tcm":
-		result.Content = cs.getStringValue(rawContent, "tcm_text")
-		result.Interpretation = cs.getStringValue(rawContent, "interpretation")
-		result.KeyWords = cs.parseKeyItems(rawContent, "key_concepts", "concept", "meaning")
-	}
-
-	if result.Content == "" {
-		return nil, fmt.Errorf("解析后的主要内容为空")
-	}
-
-	if result.Interpretation == "" {
-		return nil, fmt.Errorf("解析后的释义为空")
-	}
-
-	return result, nil
-}
-
-func (cs *ContentScheduler) getStringValue(data map[string]interface{}, key string) string {
-	if value, exists := data[key]; exists {
-		if str, ok := value.(string); ok {
-			return str
-		}
-	}
-	return ""
-}
-
-func (cs *ContentScheduler) parseKeyItems(data map[string]interface{}, arrayKey, itemKey, meaningKey string) []string {
-	var result []string
-
-	if value, exists := data[arrayKey]; exists {
-		if array, ok := value.([]interface{}); ok {
-			for _, item := range array {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					itemValue := cs.getStringValue(itemMap, itemKey)
-					meaningValue := cs.getStringValue(itemMap, meaningKey)
-					if itemValue != "" && meaningValue != "" {
-						result = append(result, fmt.Sprintf("%s: %s", itemValue, meaningValue))
-					}
-				} else if str, ok := item.(string); ok {
-					result = append(result, str)
-				}
-			}
-		}
+func (cs *ContentScheduler) TriggerUpdate() {
+	cs.mu.Lock()
+	ctx := cs.ctx
+	cs.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	return result
-}
-
-func (cs *ContentScheduler) TriggerUpdate() {
 	log.Println("🔧 手动触发内容更新...")
-	go cs.updateAllContent()
+	go cs.updateAllContent(ctx)
 }
 
 func (cs *ContentScheduler) GetNextUpdateTime() time.Time {
@@ -342,10 +164,3 @@ func (cs *ContentScheduler) GetNextUpdateTime() time.Time {
 	nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
 	return nextMidnight
 }
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
\ No newline at end of file
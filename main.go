@@ -10,8 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"everyday-study-backend/internal/auth"
 	"everyday-study-backend/internal/config"
 	"everyday-study-backend/internal/database"
+	"everyday-study-backend/internal/generator"
 	"everyday-study-backend/internal/handlers"
 	"everyday-study-backend/internal/middleware"
 	"everyday-study-backend/internal/models"
@@ -52,12 +54,22 @@ func main() {
 
 	router.Use(middleware.ErrorHandler())
 
-	handler := handlers.New(db)
+	// appCtx 贯穿后台生成流程，HTTP 服务关闭后取消以中断仍在进行的生成
+	appCtx, cancelApp := context.WithCancel(context.Background())
+	defer cancelApp()
+
+	contentGenerator := generator.New(appCtx, cfg)
+	contentGenerator.ResumeJobs(appCtx)
+	var tokens *auth.Manager
+	if cfg.UsersEnabled() {
+		tokens = auth.NewManager(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	}
+	handler := handlers.New(db, contentGenerator, tokens)
 
 	var contentScheduler *scheduler.ContentScheduler
 	if cfg.Environment == "production" {
-		contentScheduler = scheduler.NewContentScheduler(cfg)
-		contentScheduler.Start()
+		contentScheduler = scheduler.NewContentScheduler(contentGenerator)
+		contentScheduler.Start(appCtx)
 		log.Println("✅ 定时更新任务已启用")
 	} else {
 		log.Println("ℹ️  开发环境，定时更新任务已禁用")
@@ -74,12 +86,122 @@ func main() {
 	})
 
 	api := router.Group("/api")
+	if tokens != nil {
+		// 登录是可选的：携带令牌时学习历史等按用户区分，今日内容仍全站共享
+		api.Use(middleware.OptionalUser(tokens))
+	}
 	{
 		api.GET("/health", handler.Health)
 		api.GET("/today-learning/:type", handler.GetTodayLearning)
+		api.GET("/today-learning/:type/stream", handler.StreamTodayLearning)
+		api.POST("/today-learning/:type/feedback", handler.SubmitFeedback)
+		api.GET("/jobs/:id", handler.GetJob)
 		api.GET("/learning-history", handler.GetLearningHistory)
 		api.GET("/learning-history/:type", handler.GetLearningHistoryByType)
+		api.GET("/learning/:type/:date/versions", handler.GetLearningVersions)
 		api.GET("/stats", handler.GetGlobalStats)
+		api.GET("/stats/calendar", handler.GetStatsCalendar)
+		api.GET("/curricula", handler.GetCurricula)
+		api.GET("/curricula/:name", handler.GetCurriculum)
+		api.GET("/quiz/:type/:date", handler.GetQuiz)
+		api.POST("/quiz/:id/answers", handler.SubmitQuizAnswers)
+		api.GET("/glossary", handler.GetGlossary)
+		api.GET("/glossary/:term", handler.GetGlossaryTerm)
+	}
+
+	if tokens != nil {
+		// 认证接口不解析 access token，过期的令牌不影响刷新和重新登录
+		authGroup := router.Group("/api/auth")
+		{
+			authGroup.POST("/register", handler.Register)
+			authGroup.POST("/login", handler.Login)
+			authGroup.POST("/refresh", handler.RefreshToken)
+			authGroup.POST("/logout", handler.Logout)
+		}
+
+		me := api.Group("/me", middleware.RequireUser())
+		{
+			me.GET("", handler.GetMe)
+			me.GET("/records/:id", handler.GetMyRecord)
+			me.POST("/records/:id/studied", handler.MarkStudied)
+			me.DELETE("/records/:id/studied", handler.UnmarkStudied)
+			me.POST("/records/:id/favorite", handler.AddFavorite)
+			me.DELETE("/records/:id/favorite", handler.RemoveFavorite)
+			me.PUT("/records/:id/note", handler.SaveNote)
+			me.DELETE("/records/:id/note", handler.DeleteNote)
+			me.GET("/favorites", handler.ListFavorites)
+			me.GET("/notes", handler.ListNotes)
+			me.GET("/review", handler.GetReviewQueue)
+			me.POST("/review/:id", handler.SubmitReview)
+			me.GET("/quiz-results", handler.ListMyQuizResults)
+		}
+
+		log.Println("👤 用户功能已启用:")
+		log.Println("   POST /api/auth/register|login|refresh|logout - 注册、登录、刷新令牌、退出登录")
+		log.Println("   GET  /api/me - 查看当前用户")
+		log.Println("   POST/DELETE /api/me/records/:id/studied|favorite - 标记学会、收藏")
+		log.Println("   PUT/DELETE /api/me/records/:id/note - 保存、删除笔记")
+		log.Println("   GET  /api/me/favorites, /api/me/notes - 查看、搜索收藏和笔记")
+		log.Println("   GET  /api/me/review, POST /api/me/review/:id - 间隔复习队列、提交回忆评分")
+		log.Println("   GET  /api/me/quiz-results - 查看练习成绩")
+	} else {
+		log.Println("👤 未配置 JWT_SECRET，用户功能未启用，所有接口按匿名访问")
+	}
+
+	if cfg.AdminEnabled() {
+		admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminTokens, cfg.AdminHMACKeys))
+		{
+			admin.GET("/records", handler.AdminListRecords)
+			admin.GET("/records/:id", handler.AdminGetRecord)
+			admin.POST("/records", handler.AdminCreateRecord)
+			admin.PUT("/records/:id", handler.AdminUpdateRecord)
+			admin.DELETE("/records/:id", handler.AdminDeleteRecord)
+			admin.GET("/learned", handler.AdminListLearned)
+			admin.GET("/learned/:id", handler.AdminGetLearned)
+			admin.POST("/learned", handler.AdminCreateLearned)
+			admin.PUT("/learned/:id", handler.AdminUpdateLearned)
+			admin.DELETE("/learned/:id", handler.AdminDeleteLearned)
+			admin.POST("/regenerate/:type", handler.AdminRegenerate)
+			admin.POST("/pins", handler.AdminPinRecord)
+			admin.GET("/overrides", handler.AdminListOverrides)
+			admin.POST("/overrides", handler.AdminCreateOverride)
+			admin.PUT("/overrides/:id", handler.AdminUpdateOverride)
+			admin.DELETE("/overrides/:id", handler.AdminDeleteOverride)
+			admin.GET("/calendar", handler.AdminCalendar)
+			admin.POST("/curricula/:name/activate", handler.AdminActivateCurriculum)
+			admin.POST("/curricula/:name/deactivate", handler.AdminDeactivateCurriculum)
+			admin.PUT("/curricula/:name/progress", handler.AdminSetCurriculumProgress)
+			admin.GET("/audit", handler.AdminListAudit)
+			admin.POST("/audit/:id/restore", handler.AdminRestoreAudit)
+
+			admin.GET("/usage", handler.AdminUsage)
+			admin.GET("/attempts", handler.AdminListAttempts)
+			admin.GET("/attempts/:id", handler.AdminGetAttempt)
+			admin.GET("/experiments", handler.AdminListExperiments)
+			admin.POST("/experiments/:type/promote", handler.AdminPromoteVariant)
+			admin.DELETE("/experiments/:type/promote", handler.AdminClearPromotion)
+		}
+
+		log.Println("🔐 管理接口已启用（需 Bearer Token 或 HMAC 签名）:")
+		log.Println("   GET/POST /admin/records - 查看、创建学习记录")
+		log.Println("   GET/PUT/DELETE /admin/records/:id - 查看、修改、删除学习记录")
+		log.Println("   GET/POST /admin/learned - 查看、创建已学习内容")
+		log.Println("   GET/PUT/DELETE /admin/learned/:id - 查看、修改、删除已学习内容")
+		log.Println("   POST /admin/regenerate/:type - 重新生成今日内容")
+		log.Println("   POST /admin/pins - 把内容固定到指定日期")
+		log.Println("   GET/POST /admin/overrides - 查看、创建特殊日期的预设内容或主题")
+		log.Println("   PUT/DELETE /admin/overrides/:id - 修改、删除预设")
+		log.Println("   GET  /admin/calendar - 查看节气、节日和已有预设")
+		log.Println("   POST /admin/curricula/:name/activate|deactivate - 启用、停用学习路径")
+		log.Println("   PUT  /admin/curricula/:name/progress - 调整学习路径进度")
+		log.Println("   GET  /admin/audit - 查看审计日志")
+		log.Println("   POST /admin/audit/:id/restore - 按审计日志恢复数据")
+		log.Println("   GET  /admin/usage - 查看token用量与预算")
+		log.Println("   GET  /admin/attempts - 查看最近的模型调用记录")
+		log.Println("   GET  /admin/attempts/:id - 查看模型调用记录（含推理过程）")
+		log.Println("   GET  /admin/experiments - 查看提示词实验数据")
+		log.Println("   POST /admin/experiments/:type/promote - 提升实验版本")
+		log.Println("   DELETE /admin/experiments/:type/promote - 恢复按权重分流")
 	}
 
 	if cfg.Environment == "development" {
@@ -122,6 +244,7 @@ func main() {
 		port = cfg.Port
 	}
 
+	// 请求的 context 不从 appCtx 派生，关闭时先让进行中的请求处理完，再取消后台生成
 	srv := &http.Server{
 		Addr:    "0.0.0.0:" + port,
 		Handler: router,
@@ -137,12 +260,30 @@ func main() {
 	fmt.Println("📊 安全API接口:")
 	fmt.Println("   GET  / - 励志首页")
 	fmt.Println("   GET  /api/health - 健康检查")
-	fmt.Println("   GET  /api/today-learning/{type} - 获取今日学习内容")
+	fmt.Println("   GET  /api/today-learning/{type} - 获取今日学习内容（?async=true 异步生成）")
+	fmt.Println("   GET  /api/today-learning/{type}/stream - 以 SSE 推送今日内容生成进度")
+	fmt.Println("   POST /api/today-learning/{type}/feedback - 反馈今日内容是否有帮助")
+	fmt.Println("   GET  /api/jobs/{id} - 查询异步生成任务状态")
 	fmt.Println("   GET  /api/learning-history - 获取所有学习历史")
 	fmt.Println("   GET  /api/learning-history/{type} - 获取指定类型学习历史")
+	fmt.Println("   GET  /api/learning/{type}/{date}/versions - 查看某天内容的全部版本")
 	fmt.Println("   GET  /api/stats - 获取全局统计")
+	fmt.Println("   GET  /api/stats/calendar - 学习日历、连续天数和分布统计（?year=&type=）")
+	fmt.Println("   GET  /api/curricula - 查看学习路径及进度")
+	fmt.Println("   GET  /api/curricula/{name} - 查看学习路径的各单元")
+	fmt.Println("   GET  /api/quiz/{type}/{date} - 获取某天内容的练习题（?model=true 附带模型出的题）")
+	fmt.Println("   POST /api/quiz/{id}/answers - 提交练习答案并判分")
+	fmt.Println("   GET  /api/glossary - 关键词词汇表（?q= 搜索）")
+	fmt.Println("   GET  /api/glossary/{term} - 查看词条的全部释义和出处")
 	fmt.Println("📚 支持的学习类型: english, chinese, tcm")
-	fmt.Println("🛡️  安全特性: 已移除所有管理和调试接口")
+	if cfg.AdminEnabled() {
+		fmt.Printf("🛡️  管理接口: 已启用，令牌 %d 个，HMAC 密钥 %d 个\n", len(cfg.AdminTokens), len(cfg.AdminHMACKeys))
+	} else {
+		fmt.Println("🛡️  管理接口: 未配置 ADMIN_TOKENS 或 ADMIN_HMAC_KEYS，未启用")
+	}
+	if cfg.Environment == "development" {
+		fmt.Println("🔧 调试接口: 开发环境已启用 /debug")
+	}
 	fmt.Println("🌐 CORS: 已配置支持跨域请求")
 	fmt.Printf("🔑 API密钥: %s\n", maskAPIKey(cfg.VolcanoAPIKey))
 
@@ -171,6 +312,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("❌ 服务器强制关闭: %v", err)
 	}
+	cancelApp()
 
 	select {
 	case <-ctx.Done():